@@ -2,22 +2,32 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/accrual"
 	"github.com/morzisorn/gofermart/internal/client"
 	"github.com/morzisorn/gofermart/internal/controllers"
+	"github.com/morzisorn/gofermart/internal/keyring"
 	"github.com/morzisorn/gofermart/internal/logger"
+	"github.com/morzisorn/gofermart/internal/models"
 	"github.com/morzisorn/gofermart/internal/repositories"
+	"github.com/morzisorn/gofermart/internal/services/adjustments"
+	"github.com/morzisorn/gofermart/internal/services/idempotency"
+	"github.com/morzisorn/gofermart/internal/services/ledger"
+	"github.com/morzisorn/gofermart/internal/services/loginguard"
 	"github.com/morzisorn/gofermart/internal/services/orders"
+	"github.com/morzisorn/gofermart/internal/services/outbox"
 	"github.com/morzisorn/gofermart/internal/services/processing"
 	"github.com/morzisorn/gofermart/internal/services/users"
+	"github.com/morzisorn/gofermart/internal/validation"
 	"go.uber.org/zap"
 )
 
@@ -27,120 +37,325 @@ func main() {
 	}
 	cnfg := config.GetConfig()
 
-	var accrualCmd *exec.Cmd
+	if len(cnfg.Command) > 0 && cnfg.Command[0] == "migrate" {
+		if err := runMigrate(cnfg, cnfg.Command[1:]); err != nil {
+			logger.Log.Fatal("Migration failed", zap.Error(err))
+		}
+		return
+	}
+
+	if len(cnfg.Command) > 0 && cnfg.Command[0] == "role" {
+		if err := runRole(cnfg, cnfg.Command[1:]); err != nil {
+			logger.Log.Fatal("Role change failed", zap.Error(err))
+		}
+		return
+	}
+
+	validator, err := validation.NewValidator(cnfg)
+	if err != nil {
+		logger.Log.Fatal("Failed to load validation rules", zap.Error(err))
+	}
+
+	if err := keyring.Init(cnfg); err != nil {
+		logger.Log.Fatal("Failed to load JWT keys", zap.Error(err))
+	}
 
 	repo := repositories.NewRepository(cnfg)
 
-	userService := users.NewUserService(repo)
-	userController := controllers.NewUserController(userService)
+	ledgerService := ledger.NewLedgerService(repo)
+
+	var guardStore loginguard.Store = repo
+	if cnfg.LoginGuardStore == config.LoginGuardStoreMemory {
+		guardStore = loginguard.NewMemoryStore()
+	}
+	loginGuardService := loginguard.NewLoginGuardService(guardStore, cnfg)
+
+	userService := users.NewUserService(repo, ledgerService, validator, loginGuardService)
+	userController := controllers.NewUserController(userService, cnfg)
 
-	orderService := orders.NewOrderService(repo, userService)
+	orderService := orders.NewOrderService(repo)
 	orderController := controllers.NewOrderController(orderService)
 
+	adjustmentService := adjustments.NewAdjustmentService(repo)
+	adjustmentController := controllers.NewAdjustmentController(adjustmentService)
+
+	adminController := controllers.NewAdminController(userService, orderService, adjustmentService, ledgerService)
+
 	client := client.NewClient(cnfg)
 
 	processingService := processing.NewProcessingService(orderService, client)
+	breaker := processing.NewBreaker(cnfg)
 
-	mux := createServer(userController, orderController)
+	var relayService *outbox.RelayService
+	if sink := outbox.NewSink(cnfg); sink != nil {
+		relayService = outbox.NewRelayService(repo, sink, cnfg)
+	}
 
-	accrualCmd, err := createAccrualServer(cnfg)
+	idempotencyService := idempotency.NewIdempotencyService(repo, cnfg)
 
-	defer killProcess(accrualCmd)
+	mux := createServer(cnfg, userController, orderController, adjustmentController, adminController, idempotencyService, userService, breaker)
 
-	if err != nil {
-		logger.Log.Fatal("Failed to create accrual server")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var supervisor *accrual.Supervisor
+	if cnfg.AccrualSpawn {
+		supervisor = accrual.NewSupervisor(cnfg)
+		if err := supervisor.Start(context.Background()); err != nil {
+			logger.Log.Fatal("Failed to start accrual server", zap.Error(err))
+		}
 	}
 
-	if err := accrualCmd.Start(); err != nil {
-		logger.Log.Fatal("Failed to start accrual server. ", zap.Error(err))
+	var workers sync.WaitGroup
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+
+		if supervisor != nil {
+			if err := supervisor.WaitHealthy(ctx); err != nil {
+				logger.Log.Error("Accrual server is not healthy", zap.Error(err))
+				return
+			}
+		}
+		runProcessing(ctx, processingService, breaker, orderService.Uploaded(), cnfg)
+	}()
+
+	if relayService != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			runRelay(ctx, relayService, cnfg)
+		}()
 	}
 
-	go runProcessing(context.Background(), processingService, cnfg)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		runCleanup(ctx, cnfg, []cleanupJob{
+			{name: "sessions", run: userService.DeleteStaleSessions},
+			{name: "login attempts", run: loginGuardService.DeleteStaleAttempts},
+		})
+	}()
+
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
 
-	if err := runServer(mux, cnfg); err != nil {
+	if err := runServer(ctx, mux, cnfg); err != nil {
 		logger.Log.Error("Error running server", zap.Error(err))
-		err := accrualCmd.Process.Kill()
-		if err != nil {
-			logger.Log.Error("Failed to kill accrual process", zap.Error(err))
-		} else {
-			logger.Log.Info("Accrual process killed")
-		}
 	}
+
+	shutdown(stop, workersDone, repo, supervisor, cnfg)
 }
 
-func createAccrualServer(cnfg *config.Config) (*exec.Cmd, error) {
-	root, err := config.GetProjectRoot()
-	if err != nil {
-		return nil, fmt.Errorf("get project root error: %w", err)
-	}
+// shutdown stops components in reverse order of dependencies: background workers, database, accrual
+func shutdown(
+	stop context.CancelFunc,
+	workersDone chan struct{},
+	repo repositories.Repository,
+	supervisor *accrual.Supervisor,
+	cnfg *config.Config,
+) {
+	stop()
 
-	filename := fmt.Sprintf("accrual_%s_%s", runtime.GOOS, runtime.GOARCH)
-	path := filepath.Join(root, "cmd", "accrual", filename)
-	logger.Log.Info("Accrual binary path", zap.String("path", path))
+	select {
+	case <-workersDone:
+	case <-time.After(time.Duration(cnfg.ShutdownTimeout) * time.Second):
+		logger.Log.Warn("Background workers did not stop in time")
+	}
 
-	accrualCmd := exec.Command(path, "-a", cnfg.AccrualSystemAddress)
-	accrualCmd.Env = []string{}
+	repo.Close()
 
-	accrualCmd.Stdout = os.Stdout
-	accrualCmd.Stderr = os.Stderr
+	if supervisor != nil {
+		supervisor.Stop()
+	}
 
-	return accrualCmd, nil
+	logger.Log.Info("Server stopped")
 }
 
 func createServer(
+	cnfg *config.Config,
 	uc *controllers.UserController,
 	oc *controllers.OrderController,
+	adc *controllers.AdjustmentController,
+	ac *controllers.AdminController,
+	is *idempotency.IdempotencyService,
+	sessions controllers.SessionChecker,
+	processing controllers.ProcessingStatusReporter,
 ) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	mux := gin.Default()
 
+	// Client IP is used for login throttling, it must not be taken from headers of arbitrary clients
+	if err := mux.SetTrustedProxies(cnfg.TrustedProxies); err != nil {
+		logger.Log.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+
+	mux.Use(controllers.RequestTimeout(cnfg))
+
+	mux.GET("/.well-known/jwks.json", controllers.JWKS)
+	mux.GET("/health", controllers.Health(processing))
+
 	mux.POST("/api/user/register", uc.RegisterUser)
 	mux.POST("/api/user/login", uc.Login)
+	mux.POST("/api/user/token/refresh", uc.RefreshToken)
 
-	authGroup := mux.Group("/api/user", controllers.AuthMiddleware())
+	mux.DELETE("/api/user", controllers.AuthMiddleware(sessions, cnfg), uc.DeleteUser)
+
+	authGroup := mux.Group("/api/user", controllers.AuthMiddleware(sessions, cnfg))
 	{
+		authGroup.POST("/logout", uc.Logout)
+		authGroup.PUT("/password", uc.ChangePassword)
+
 		authGroup.GET("/balance", uc.GetBalance)
 
 		authGroup.POST("/orders", controllers.RequireContentType("text/plain"), oc.UploadOrder)
-		authGroup.POST("/balance/withdraw", oc.Withdraw)
+		authGroup.POST("/balance/withdraw", controllers.Idempotency(is), oc.Withdraw)
 		authGroup.GET("/orders", oc.GetUserOrders)
 		authGroup.GET("/withdrawals", oc.GetUserWithdrawals)
+		authGroup.GET("/adjustments", adc.GetUserAdjustments)
+	}
+
+	adminGroup := mux.Group("/api/admin",
+		controllers.AuthMiddleware(sessions, cnfg),
+		controllers.RequireRole(models.RoleSupport, models.RoleAdmin),
+	)
+	{
+		adminGroup.GET("/users/:login/balance", ac.GetUserBalance)
+		adminGroup.GET("/users/:login/orders", ac.GetUserOrders)
+		adminGroup.GET("/users/:login/withdrawals", ac.GetUserWithdrawals)
+		adminGroup.GET("/users/:login/adjustments", ac.GetUserAdjustments)
+		adminGroup.GET("/users/:login/ledger", ac.GetUserLedger)
+		adminGroup.GET("/orders/parked", ac.GetParkedOrders)
+
+		adminGroup.POST("/users/:login/adjustments", controllers.RequireRole(models.RoleAdmin), controllers.Idempotency(is), ac.AdjustBalance)
+		adminGroup.PUT("/users/:login/role", controllers.RequireRole(models.RoleAdmin), ac.SetUserRole)
 	}
 
 	return mux
 }
 
-func runServer(mux *gin.Engine, cnfg *config.Config) error {
-	logger.Log.Info("Starting server on ", zap.String("address", cnfg.RunAddress))
+func runServer(ctx context.Context, mux *gin.Engine, cnfg *config.Config) error {
+	srv := &http.Server{
+		Addr:    cnfg.RunAddress,
+		Handler: mux,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Log.Info("Starting server on ", zap.String("address", cnfg.RunAddress))
+		errCh <- srv.ListenAndServe()
+	}()
 
-	return mux.Run(cnfg.RunAddress)
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Log.Info("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cnfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
-func runProcessing(ctx context.Context, ps *processing.ProcessingService, cnfg *config.Config) {
+// runProcessing drains processing queue every LoyaltyUpdateInterval and right after an order is uploaded.
+// Failed runs are retried with backoff of breaker instead of on every tick.
+func runProcessing(ctx context.Context, ps *processing.ProcessingService, breaker *processing.Breaker, uploaded <-chan struct{}, cnfg *config.Config) {
 	ticker := time.NewTicker(time.Duration(cnfg.LoyaltyUpdateInterval) * time.Second)
 	defer ticker.Stop()
 
+	var retry <-chan time.Time
+
 	for {
 		select {
 		case <-ctx.Done():
 			logger.Log.Info("Context canceled, stopping processing loop")
 			return
 		case <-ticker.C:
-			err := ps.ProcessOrders(context.Background())
-			if err != nil {
-				logger.Log.Error("Processing error: ", zap.Error(err))
-			}
+		case <-uploaded:
+		case <-retry:
+		}
+
+		if !breaker.Allow() {
+			continue
+		}
+		retry = nil
+
+		err := ps.ProcessOrders(ctx)
+		if err != nil {
+			delay := breaker.Failure()
+			logger.Log.Error("Processing error: ",
+				zap.Error(err),
+				zap.Int("failures", breaker.Status().Failures),
+				zap.Duration("retry_in", delay),
+			)
+			retry = time.After(delay)
+			continue
 		}
+		breaker.Success()
 	}
 }
 
-func killProcess(p *exec.Cmd) {
-	if p != nil && p.Process != nil {
-		err := p.Process.Kill()
-		if err != nil {
-			logger.Log.Error("Failed to kill accrual process", zap.Error(err))
-		} else {
-			logger.Log.Info("Accrual process killed")
+// runRelay publishes outbox events every OutboxInterval
+func runRelay(ctx context.Context, rs *outbox.RelayService, cnfg *config.Config) {
+	ticker := time.NewTicker(time.Duration(cnfg.OutboxInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info("Context canceled, stopping outbox relay")
+			return
+		case <-ticker.C:
+		}
+
+		if err := rs.Relay(ctx); err != nil {
+			logger.Log.Error("Outbox relay error: ", zap.Error(err))
+		}
+	}
+}
+
+// cleanupJob deletes stale rows and reports how many were deleted
+type cleanupJob struct {
+	name string
+	run  func(ctx context.Context) (int64, error)
+}
+
+// runCleanup runs cleanup jobs every CleanupInterval, off the request path
+func runCleanup(ctx context.Context, cnfg *config.Config, jobs []cleanupJob) {
+	ticker := time.NewTicker(time.Duration(cnfg.CleanupInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info("Context canceled, stopping cleanup")
+			return
+		case <-ticker.C:
+		}
+
+		for _, job := range jobs {
+			deleted, err := job.run(ctx)
+			if err != nil {
+				logger.Log.Error("Cleanup error: ", zap.String("job", job.name), zap.Error(err))
+				continue
+			}
+			if deleted > 0 {
+				logger.Log.Info("Stale rows deleted", zap.String("job", job.name), zap.Int64("deleted", deleted))
+			}
 		}
 	}
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestStartOutsideSourceTree checks that the binary needs nothing from the repository checkout.
+// Requires TEST_DATABASE_URI pointing to a disposable PostgreSQL database.
+func TestStartOutsideSourceTree(t *testing.T) {
+	dbURI := os.Getenv("TEST_DATABASE_URI")
+	if dbURI == "" {
+		t.Skip("TEST_DATABASE_URI is not set")
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "gophermart")
+
+	build := exec.Command("go", "build", "-o", bin, ".")
+	out, err := build.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	accrual := filepath.Join(dir, "accrual")
+	require.NoError(t, os.WriteFile(accrual, []byte("#!/bin/sh\nexec sleep 30\n"), 0o755))
+
+	addr := freeAddress(t)
+
+	cmd := exec.Command(bin, "-a", addr, "-d", dbURI, "--accrual-binary", accrual)
+	cmd.Dir = dir
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH")}
+	require.NoError(t, cmd.Start())
+	defer func() { _ = cmd.Process.Kill() }()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Post("http://"+addr+"/api/user/login", "application/json", strings.NewReader("{}"))
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode == http.StatusUnauthorized
+	}, 15*time.Second, 100*time.Millisecond)
+
+	_, err = os.Stat(filepath.Join(dir, "go.mod"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func freeAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
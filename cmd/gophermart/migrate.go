@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/logger"
+	"github.com/morzisorn/gofermart/internal/repositories/database/migrations"
+	"go.uber.org/zap"
+)
+
+// runMigrate handles `gophermart migrate [up | down [N] | version]`
+func runMigrate(cnfg *config.Config, args []string) error {
+	ctx := context.Background()
+
+	db, err := pgxpool.New(ctx, cnfg.DatabaseURI)
+	if err != nil {
+		return fmt.Errorf("connect to db error: %w", err)
+	}
+	defer db.Close()
+
+	m, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+		return m.Down(ctx, steps)
+	case "version":
+		version, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Log.Info("Current schema version", zap.Int64("version", version))
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command: %s", cmd)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/repositories"
+	"github.com/morzisorn/gofermart/internal/services/users"
+)
+
+// runRole handles `gophermart role <login> <user|support|admin>`, first admin can only be granted this way
+func runRole(cnfg *config.Config, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: gophermart role <login> <user|support|admin>")
+	}
+
+	repo := repositories.NewRepository(cnfg)
+	defer repo.Close()
+
+	// Role change needs only the repository
+	us := users.NewUserService(repo, nil, nil, nil)
+
+	return us.SetUserRole(context.Background(), args[0], args[1])
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 
 	"github.com/morzisorn/gofermart/internal/logger"
@@ -14,12 +15,92 @@ type Config struct {
 	RunAddress           string
 	DatabaseURI          string
 	AccrualSystemAddress string
+	AccrualBinaryPath    string
+	AccrualSpawn         bool //Run bundled accrual binary under supervisor
 
 	SecretKey             string
-	RateLimit             int //Processing workers rate limit
-	LoyaltyUpdateInterval int //Loyalty update interval in seconds
+	PasswordPepper        string   //Pepper of legacy SHA-256 password hashes, defaults to SecretKey
+	JWTAlgorithm          string   //HS256, RS256 or EdDSA
+	JWTKeys               []string //kid:secret or kid:/path/to/private.pem, first one signs new tokens
+	AccessTokenTTL        int      //Access token lifetime in seconds
+	RefreshTokenTTL       int      //Refresh token lifetime in seconds
+	AuthCookies           bool     //Set tokens as HttpOnly cookies instead of response body for browser clients
+	CookieSecure          bool     //Secure attribute of auth cookies, disable only for local HTTP
+	RateLimit             int      //Processing workers rate limit
+	LoyaltyUpdateInterval int      //Seconds between checks of one order and between queue polls
+	JobBatchSize          int      //Orders claimed from processing queue at once
+	JobLease              int      //Seconds a claimed order is hidden from other replicas
+	ShutdownTimeout       int      //Seconds to drain requests and processing on shutdown
+	CleanupInterval       int      //Seconds between deletions of stale rows
+
+	RequestTimeout int            //Default request deadline in seconds
+	RouteTimeouts  map[string]int //Per-route deadlines in seconds keyed by "METHOD /path"
+
+	IdempotencyWindow int //Seconds to keep Idempotency-Key responses
+	IdempotencyLease  int //Seconds an unfinished request holds its key, must outlive request deadlines
+
+	LoginPattern         string //Regular expression of allowed login characters
+	LoginMinLength       int
+	LoginMaxLength       int //Can not exceed users.login column size
+	PasswordMinLength    int
+	PasswordMinClasses   int    //Required character classes: lowercase, uppercase, digits, other
+	PasswordDenylistPath string //File with breached passwords, one per line
+
+	LoginGuardStore    string   //Where failed login counters live: postgres or memory
+	LoginMaxAttempts   int      //Failures per login before lockout
+	LoginIPMaxAttempts int      //Failures per client IP before lockout
+	LoginLockoutBase   int      //First lockout in seconds, doubles with every next failure
+	LoginLockoutMax    int      //Lockout cap in seconds
+	LoginFailureWindow int      //Seconds without failures after which counter starts over
+	TrustedProxies     []string //Proxies allowed to set client IP in X-Forwarded-For, none by default
+
+	UnregisteredGracePeriod int    //Seconds to keep polling orders unknown to accrual
+	UnregisteredPolicy      string //What to do with order after grace period: invalid or park
+
+	OrderBackoff     string //Delay growth between checks of one order: constant, linear or exponential
+	OrderBackoffBase int    //Delay after first check in seconds
+	OrderBackoffMax  int    //Delay cap in seconds
+	OrderMaxAge      int    //Seconds after upload when unfinished order is parked as stuck, 0 disables
+
+	ProcessingBreakerThreshold int //Failed processing runs in a row before circuit opens
+	ProcessingBackoffBase      int //Seconds before retry after first failed run, doubles with every next one
+	ProcessingBackoffMax       int //Backoff cap in seconds, also time circuit stays open
+
+	OutboxSink          string //Where relay publishes events: webhook, file or empty to not record events
+	OutboxWebhookURL    string
+	OutboxWebhookSecret string //Key of HMAC-SHA256 signature in X-Signature header, no signature if empty
+	OutboxFilePath      string //NDJSON file events are appended to
+	OutboxInterval      int    //Seconds between relay runs
+	OutboxBatchSize     int    //Events claimed by relay at once
+	OutboxLease         int    //Seconds a claimed event is hidden from other replicas
+	OutboxBackoffMax    int    //Cap in seconds of delay before retry of failed event
+	OutboxRetention     int    //Seconds to keep published events
+
+	Command []string //Positional arguments, e.g. migrate up
 }
 
+const (
+	LoginGuardStorePostgres = "postgres"
+	LoginGuardStoreMemory   = "memory"
+)
+
+const (
+	UnregisteredPolicyInvalid = "invalid"
+	UnregisteredPolicyPark    = "park"
+)
+
+const (
+	OutboxSinkNone    = ""
+	OutboxSinkWebhook = "webhook"
+	OutboxSinkFile    = "file"
+)
+
+const (
+	OrderBackoffConstant    = "constant"
+	OrderBackoffLinear      = "linear"
+	OrderBackoffExponential = "exponential"
+)
+
 var (
 	instance *Config
 	once     sync.Once
@@ -42,9 +123,10 @@ func GetConfig() *Config {
 }
 
 func New() (*Config, error) {
-	envPath := getEncFilePath()
+	envPath, explicit := getEnvFilePath()
 
-	if err := loadEnvFile(envPath); err != nil {
+	// Default .env is a development convenience, it is absent next to a shipped binary
+	if err := loadEnvFile(envPath); err != nil && explicit {
 		fmt.Printf("Load .env error: %v. Env path: %s\n", err, envPath)
 	}
 
@@ -57,30 +139,15 @@ func New() (*Config, error) {
 	return c, nil
 }
 
-func getEncFilePath() string {
-	basePath, err := GetProjectRoot()
-	if err != nil {
-		logger.Log.Error("Error getting project root ", zap.Error(err))
-		return ".env"
+// getEnvFilePath returns ENV_FILE if set, otherwise config/.env relative to working directory
+func getEnvFilePath() (string, bool) {
+	if path := os.Getenv("ENV_FILE"); path != "" {
+		return path, true
 	}
-	return filepath.Join(basePath, "config", ".env")
+	return filepath.Join("config", ".env"), false
 }
 
-func GetProjectRoot() (string, error) {
-	wd, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
-
-	for {
-		if _, err := os.Stat(filepath.Join(wd, "go.mod")); err == nil {
-			return wd, nil
-		}
-
-		parent := filepath.Dir(wd)
-		if parent == wd {
-			return "", fmt.Errorf("project root not found")
-		}
-		wd = parent
-	}
+func defaultAccrualBinaryPath() string {
+	filename := fmt.Sprintf("accrual_%s_%s", runtime.GOOS, runtime.GOARCH)
+	return filepath.Join("cmd", "accrual", filename)
 }
@@ -39,11 +39,59 @@ func (c *Config) parseEnv() error {
 		}
 	}
 
+	accrualBinary, err := getEnvString("ACCRUAL_BINARY_PATH")
+	if err == nil {
+		c.AccrualBinaryPath = accrualBinary
+	}
+
+	spawn, err := getEnvBool("ACCRUAL_SPAWN")
+	if err == nil {
+		c.AccrualSpawn = spawn
+	}
+
 	key, err := getEnvString("SECRET_KEY")
 	if err == nil {
 		c.SecretKey = key
 	}
 
+	pepper, err := getEnvString("PASSWORD_PEPPER")
+	if err == nil {
+		c.PasswordPepper = pepper
+	}
+	if c.PasswordPepper == "" {
+		c.PasswordPepper = c.SecretKey
+	}
+
+	alg, err := getEnvString("JWT_ALG")
+	if err == nil {
+		c.JWTAlgorithm = alg
+	}
+
+	jwtKeys, err := getEnvString("JWT_KEYS")
+	if err == nil {
+		c.JWTKeys = strings.Split(jwtKeys, ",")
+	}
+
+	accessTTL, err := getEnvInt("ACCESS_TOKEN_TTL")
+	if err == nil {
+		c.AccessTokenTTL = int(accessTTL)
+	}
+
+	refreshTTL, err := getEnvInt("REFRESH_TOKEN_TTL")
+	if err == nil {
+		c.RefreshTokenTTL = int(refreshTTL)
+	}
+
+	authCookies, err := getEnvBool("AUTH_COOKIES")
+	if err == nil {
+		c.AuthCookies = authCookies
+	}
+
+	cookieSecure, err := getEnvBool("COOKIE_SECURE")
+	if err == nil {
+		c.CookieSecure = cookieSecure
+	}
+
 	rateLimit, err := getEnvInt("RATE_LIMIT")
 	if err == nil {
 		c.RateLimit = int(rateLimit)
@@ -54,6 +102,270 @@ func (c *Config) parseEnv() error {
 		c.LoyaltyUpdateInterval = int(interval)
 	}
 
+	jobBatch, err := getEnvInt("JOB_BATCH_SIZE")
+	if err == nil {
+		c.JobBatchSize = int(jobBatch)
+	}
+
+	jobLease, err := getEnvInt("JOB_LEASE")
+	if err == nil {
+		c.JobLease = int(jobLease)
+	}
+
+	shutdown, err := getEnvInt("SHUTDOWN_TIMEOUT")
+	if err == nil {
+		c.ShutdownTimeout = int(shutdown)
+	}
+
+	cleanup, err := getEnvInt("CLEANUP_INTERVAL")
+	if err == nil {
+		c.CleanupInterval = int(cleanup)
+	}
+
+	requestTimeout, err := getEnvInt("REQUEST_TIMEOUT")
+	if err == nil {
+		c.RequestTimeout = int(requestTimeout)
+	}
+
+	routeTimeouts, err := getEnvString("ROUTE_TIMEOUTS")
+	if err == nil {
+		c.RouteTimeouts, err = parseStringToInt(routeTimeouts)
+		if err != nil {
+			return fmt.Errorf("parse ROUTE_TIMEOUTS error: %w", err)
+		}
+	}
+
+	idempotencyWindow, err := getEnvInt("IDEMPOTENCY_WINDOW")
+	if err == nil {
+		c.IdempotencyWindow = int(idempotencyWindow)
+	}
+
+	idempotencyLease, err := getEnvInt("IDEMPOTENCY_LEASE")
+	if err == nil {
+		c.IdempotencyLease = int(idempotencyLease)
+	}
+
+	loginPattern, err := getEnvString("LOGIN_PATTERN")
+	if err == nil {
+		c.LoginPattern = loginPattern
+	}
+
+	loginMin, err := getEnvInt("LOGIN_MIN_LENGTH")
+	if err == nil {
+		c.LoginMinLength = int(loginMin)
+	}
+
+	loginMax, err := getEnvInt("LOGIN_MAX_LENGTH")
+	if err == nil {
+		c.LoginMaxLength = int(loginMax)
+	}
+
+	passwordMin, err := getEnvInt("PASSWORD_MIN_LENGTH")
+	if err == nil {
+		c.PasswordMinLength = int(passwordMin)
+	}
+
+	passwordClasses, err := getEnvInt("PASSWORD_MIN_CLASSES")
+	if err == nil {
+		c.PasswordMinClasses = int(passwordClasses)
+	}
+
+	denylist, err := getEnvString("PASSWORD_DENYLIST_PATH")
+	if err == nil {
+		c.PasswordDenylistPath = denylist
+	}
+
+	guardStore, err := getEnvString("LOGIN_GUARD_STORE")
+	if err == nil {
+		c.LoginGuardStore = guardStore
+	}
+
+	loginAttempts, err := getEnvInt("LOGIN_MAX_ATTEMPTS")
+	if err == nil {
+		c.LoginMaxAttempts = int(loginAttempts)
+	}
+
+	ipAttempts, err := getEnvInt("LOGIN_IP_MAX_ATTEMPTS")
+	if err == nil {
+		c.LoginIPMaxAttempts = int(ipAttempts)
+	}
+
+	lockout, err := getEnvInt("LOGIN_LOCKOUT")
+	if err == nil {
+		c.LoginLockoutBase = int(lockout)
+	}
+
+	lockoutMax, err := getEnvInt("LOGIN_LOCKOUT_MAX")
+	if err == nil {
+		c.LoginLockoutMax = int(lockoutMax)
+	}
+
+	failureWindow, err := getEnvInt("LOGIN_FAILURE_WINDOW")
+	if err == nil {
+		c.LoginFailureWindow = int(failureWindow)
+	}
+
+	proxies, err := getEnvString("TRUSTED_PROXIES")
+	if err == nil {
+		c.TrustedProxies = strings.Split(proxies, ",")
+	}
+
+	switch c.LoginGuardStore {
+	case LoginGuardStorePostgres, LoginGuardStoreMemory:
+	default:
+		return fmt.Errorf("unknown login guard store: %s", c.LoginGuardStore)
+	}
+
+	grace, err := getEnvInt("UNREGISTERED_GRACE_PERIOD")
+	if err == nil {
+		c.UnregisteredGracePeriod = int(grace)
+	}
+
+	policy, err := getEnvString("UNREGISTERED_POLICY")
+	if err == nil {
+		c.UnregisteredPolicy = policy
+	}
+
+	switch c.UnregisteredPolicy {
+	case UnregisteredPolicyInvalid, UnregisteredPolicyPark:
+	default:
+		return fmt.Errorf("unknown unregistered order policy: %s", c.UnregisteredPolicy)
+	}
+
+	backoff, err := getEnvString("ORDER_BACKOFF")
+	if err == nil {
+		c.OrderBackoff = backoff
+	}
+
+	backoffBase, err := getEnvInt("ORDER_BACKOFF_BASE")
+	if err == nil {
+		c.OrderBackoffBase = int(backoffBase)
+	}
+
+	backoffMax, err := getEnvInt("ORDER_BACKOFF_MAX")
+	if err == nil {
+		c.OrderBackoffMax = int(backoffMax)
+	}
+
+	maxAge, err := getEnvInt("ORDER_MAX_AGE")
+	if err == nil {
+		c.OrderMaxAge = int(maxAge)
+	}
+
+	breakerThreshold, err := getEnvInt("PROCESSING_BREAKER_THRESHOLD")
+	if err == nil {
+		c.ProcessingBreakerThreshold = int(breakerThreshold)
+	}
+
+	processingBackoff, err := getEnvInt("PROCESSING_BACKOFF")
+	if err == nil {
+		c.ProcessingBackoffBase = int(processingBackoff)
+	}
+
+	processingBackoffMax, err := getEnvInt("PROCESSING_BACKOFF_MAX")
+	if err == nil {
+		c.ProcessingBackoffMax = int(processingBackoffMax)
+	}
+
+	outboxSink, err := getEnvString("OUTBOX_SINK")
+	if err == nil {
+		c.OutboxSink = outboxSink
+	}
+
+	webhookURL, err := getEnvString("OUTBOX_WEBHOOK_URL")
+	if err == nil {
+		c.OutboxWebhookURL = webhookURL
+	}
+
+	webhookSecret, err := getEnvString("OUTBOX_WEBHOOK_SECRET")
+	if err == nil {
+		c.OutboxWebhookSecret = webhookSecret
+	}
+
+	outboxFile, err := getEnvString("OUTBOX_FILE")
+	if err == nil {
+		c.OutboxFilePath = outboxFile
+	}
+
+	outboxInterval, err := getEnvInt("OUTBOX_INTERVAL")
+	if err == nil {
+		c.OutboxInterval = int(outboxInterval)
+	}
+
+	outboxBatch, err := getEnvInt("OUTBOX_BATCH_SIZE")
+	if err == nil {
+		c.OutboxBatchSize = int(outboxBatch)
+	}
+
+	outboxLease, err := getEnvInt("OUTBOX_LEASE")
+	if err == nil {
+		c.OutboxLease = int(outboxLease)
+	}
+
+	outboxBackoff, err := getEnvInt("OUTBOX_BACKOFF_MAX")
+	if err == nil {
+		c.OutboxBackoffMax = int(outboxBackoff)
+	}
+
+	outboxRetention, err := getEnvInt("OUTBOX_RETENTION")
+	if err == nil {
+		c.OutboxRetention = int(outboxRetention)
+	}
+
+	switch c.OutboxSink {
+	case OutboxSinkNone, OutboxSinkFile:
+	case OutboxSinkWebhook:
+		if c.OutboxWebhookURL == "" {
+			return fmt.Errorf("outbox webhook sink requires webhook URL")
+		}
+	default:
+		return fmt.Errorf("unknown outbox sink: %s", c.OutboxSink)
+	}
+
+	switch {
+	case c.OutboxInterval <= 0:
+		return fmt.Errorf("outbox interval must be positive")
+	case c.OutboxBatchSize <= 0:
+		return fmt.Errorf("outbox batch size must be positive")
+	case c.OutboxLease <= 0:
+		return fmt.Errorf("outbox lease must be positive")
+	}
+
+	switch c.OrderBackoff {
+	case OrderBackoffConstant, OrderBackoffLinear, OrderBackoffExponential:
+	default:
+		return fmt.Errorf("unknown order backoff: %s", c.OrderBackoff)
+	}
+
+	switch {
+	case c.OrderBackoffBase <= 0:
+		return fmt.Errorf("order backoff base must be positive")
+	case c.OrderBackoffMax < c.OrderBackoffBase:
+		return fmt.Errorf("order backoff max must not be less than order backoff base")
+	}
+
+	switch {
+	case c.ProcessingBackoffBase <= 0:
+		return fmt.Errorf("processing backoff must be positive")
+	case c.ProcessingBackoffMax < c.ProcessingBackoffBase:
+		return fmt.Errorf("processing backoff max must not be less than processing backoff")
+	}
+
+	switch {
+	case c.JobBatchSize <= 0:
+		return fmt.Errorf("job batch size must be positive")
+	case c.JobLease <= 0:
+		return fmt.Errorf("job lease must be positive")
+	}
+
+	if c.IdempotencyLease <= 0 {
+		return fmt.Errorf("idempotency lease must be positive")
+	}
+
+	if c.CleanupInterval <= 0 {
+		return fmt.Errorf("cleanup interval must be positive")
+	}
+
 	return nil
 }
 
@@ -72,3 +384,29 @@ func getEnvInt(key string) (int64, error) {
 	}
 	return 0, fmt.Errorf("env %s not found", key)
 }
+
+func getEnvBool(key string) (bool, error) {
+	env := os.Getenv(key)
+	if env != "" {
+		return strconv.ParseBool(env)
+	}
+	return false, fmt.Errorf("env %s not found", key)
+}
+
+// parseStringToInt parses "key=1,other key=2" the same way as pflag StringToInt
+func parseStringToInt(env string) (map[string]int, error) {
+	result := make(map[string]int)
+	for _, pair := range strings.Split(env, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%s must be formatted as key=value", pair)
+		}
+
+		v, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, err
+		}
+		result[strings.TrimSpace(kv[0])] = v
+	}
+	return result, nil
+}
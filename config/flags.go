@@ -10,10 +10,70 @@ func (c *Config) parseFlags() error {
 	pflag.StringVarP(&c.RunAddress, "addr", "a", "localhost:8080", "address and port to run server")
 	pflag.StringVarP(&c.DatabaseURI, "dbstr", "d", "", "db connection string")
 	pflag.StringVarP(&c.AccrualSystemAddress, "accrual", "r", "localhost:8081", "accrual system address")
+	pflag.StringVar(&c.AccrualBinaryPath, "accrual-binary", defaultAccrualBinaryPath(), "path to accrual system binary")
+	pflag.BoolVar(&c.AccrualSpawn, "accrual-spawn", true, "run bundled accrual system binary")
 
 	pflag.StringVarP(&c.SecretKey, "key", "k", "VERY_SECRET", "secret key")
+	pflag.StringVar(&c.PasswordPepper, "password-pepper", "", "pepper of legacy password hashes, defaults to secret key")
+	pflag.StringVar(&c.JWTAlgorithm, "jwt-alg", "HS256", "JWT signing algorithm: HS256, RS256 or EdDSA")
+	pflag.IntVar(&c.AccessTokenTTL, "access-ttl", 15*60, "access token lifetime in seconds")
+	pflag.IntVar(&c.RefreshTokenTTL, "refresh-ttl", 30*24*60*60, "refresh token lifetime in seconds")
+	pflag.BoolVar(&c.AuthCookies, "auth-cookies", false, "set auth tokens as HttpOnly cookies instead of response body, with CSRF protection")
+	pflag.BoolVar(&c.CookieSecure, "cookie-secure", true, "send auth cookies over HTTPS only")
+	pflag.StringSliceVar(&c.JWTKeys, "jwt-keys", nil, "JWT keys as kid:secret or kid:/path/to/private.pem, first one signs")
 	pflag.IntVarP(&c.RateLimit, "limit", "l", 5, "loyalty updater rate limit")
 	pflag.IntVarP(&c.LoyaltyUpdateInterval, "interval", "i", 5, "loyalty update interval in seconds")
+	pflag.IntVar(&c.JobBatchSize, "job-batch", 100, "orders claimed from processing queue at once")
+	pflag.IntVar(&c.JobLease, "job-lease", 120, "seconds a claimed order is hidden from other replicas")
+	pflag.IntVar(&c.ShutdownTimeout, "shutdown-timeout", 10, "graceful shutdown timeout in seconds")
+	pflag.IntVar(&c.CleanupInterval, "cleanup-interval", 3600, "seconds between deletions of stale rows")
 
-	return pflag.CommandLine.Parse(os.Args[1:])
+	pflag.IntVar(&c.RequestTimeout, "request-timeout", 5, "default request deadline in seconds")
+	pflag.IntVar(&c.IdempotencyWindow, "idempotency-window", 86400, "seconds to keep idempotency keys")
+	pflag.IntVar(&c.IdempotencyLease, "idempotency-lease", 60, "seconds an unfinished request holds its idempotency key")
+	pflag.StringToIntVar(&c.RouteTimeouts, "route-timeouts", map[string]int{}, `per-route deadlines in seconds, e.g. "POST /api/user/balance/withdraw=10"`)
+
+	pflag.StringVar(&c.LoginPattern, "login-pattern", `^[a-zA-Z0-9_.-]+$`, "regular expression of allowed login characters")
+	pflag.IntVar(&c.LoginMinLength, "login-min", 3, "minimum login length")
+	pflag.IntVar(&c.LoginMaxLength, "login-max", 50, "maximum login length")
+	pflag.IntVar(&c.PasswordMinLength, "password-min", 8, "minimum password length")
+	pflag.IntVar(&c.PasswordMinClasses, "password-classes", 2, "required password character classes: lowercase, uppercase, digits, other")
+	pflag.StringVar(&c.PasswordDenylistPath, "password-denylist", "", "file with breached passwords, one per line")
+
+	pflag.StringVar(&c.LoginGuardStore, "login-guard-store", LoginGuardStorePostgres, "failed login counters store: postgres or memory")
+	pflag.IntVar(&c.LoginMaxAttempts, "login-max-attempts", 5, "failed logins per account before lockout")
+	pflag.IntVar(&c.LoginIPMaxAttempts, "login-ip-max-attempts", 20, "failed logins per client IP before lockout")
+	pflag.IntVar(&c.LoginLockoutBase, "login-lockout", 30, "first lockout in seconds, doubles with every next failure")
+	pflag.IntVar(&c.LoginLockoutMax, "login-lockout-max", 3600, "maximum lockout in seconds")
+	pflag.IntVar(&c.LoginFailureWindow, "login-failure-window", 3600, "seconds without failures to reset counter")
+	pflag.StringSliceVar(&c.TrustedProxies, "trusted-proxies", nil, "proxy addresses or CIDRs trusted to pass client IP")
+
+	pflag.IntVar(&c.UnregisteredGracePeriod, "unregistered-grace", 3600, "seconds to wait for order registration in accrual system")
+	pflag.StringVar(&c.UnregisteredPolicy, "unregistered-policy", UnregisteredPolicyInvalid, "unregistered order policy after grace period: invalid or park")
+
+	pflag.StringVar(&c.OrderBackoff, "order-backoff", OrderBackoffExponential, "delay growth between checks of one order: constant, linear or exponential")
+	pflag.IntVar(&c.OrderBackoffBase, "order-backoff-base", 5, "delay after first order check in seconds")
+	pflag.IntVar(&c.OrderBackoffMax, "order-backoff-max", 600, "maximum delay between order checks in seconds")
+	pflag.IntVar(&c.OrderMaxAge, "order-max-age", 7*24*60*60, "seconds after upload when unfinished order is parked as stuck, 0 disables")
+
+	pflag.IntVar(&c.ProcessingBreakerThreshold, "processing-breaker-threshold", 5, "failed processing runs in a row before circuit opens")
+	pflag.IntVar(&c.ProcessingBackoffBase, "processing-backoff", 1, "seconds before retry after failed processing run, doubles with every next one")
+	pflag.IntVar(&c.ProcessingBackoffMax, "processing-backoff-max", 60, "maximum processing backoff in seconds")
+
+	pflag.StringVar(&c.OutboxSink, "outbox-sink", OutboxSinkNone, "where to publish events: webhook, file or empty to keep them in outbox")
+	pflag.StringVar(&c.OutboxWebhookURL, "outbox-webhook-url", "", "URL events are POSTed to by webhook sink")
+	pflag.StringVar(&c.OutboxWebhookSecret, "outbox-webhook-secret", "", "key of HMAC-SHA256 signature of webhook requests")
+	pflag.StringVar(&c.OutboxFilePath, "outbox-file", "outbox.ndjson", "file events are appended to by file sink")
+	pflag.IntVar(&c.OutboxInterval, "outbox-interval", 1, "seconds between outbox relay runs")
+	pflag.IntVar(&c.OutboxBatchSize, "outbox-batch", 100, "events claimed by outbox relay at once")
+	pflag.IntVar(&c.OutboxLease, "outbox-lease", 60, "seconds a claimed event is hidden from other replicas")
+	pflag.IntVar(&c.OutboxBackoffMax, "outbox-backoff-max", 300, "maximum delay before retry of failed event in seconds")
+	pflag.IntVar(&c.OutboxRetention, "outbox-retention", 7*24*60*60, "seconds to keep published events")
+
+	if err := pflag.CommandLine.Parse(os.Args[1:]); err != nil {
+		return err
+	}
+	c.Command = pflag.Args()
+
+	return nil
 }
@@ -48,7 +48,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/spf13/pflag v1.0.6
 	go.uber.org/zap v1.27.0
-	golang.org/x/crypto v0.31.0 // indirect
+	golang.org/x/crypto v0.31.0
 	golang.org/x/text v0.21.0 // indirect
 	resty.dev/v3 v3.0.0-beta.2
 )
@@ -0,0 +1,176 @@
+package accrual
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	minBackoff      = 500 * time.Millisecond
+	maxBackoff      = 30 * time.Second
+	stableRun       = time.Minute //Run longer than this resets backoff
+	healthInterval  = 200 * time.Millisecond
+	terminateWindow = 5 * time.Second
+)
+
+// Supervisor runs bundled accrual binary, restarts it on crash with backoff
+// and forwards its output to zap.
+type Supervisor struct {
+	path    string
+	address string
+	log     *zap.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewSupervisor(cnfg *config.Config) *Supervisor {
+	return &Supervisor{
+		path:    cnfg.AccrualBinaryPath,
+		address: cnfg.AccrualSystemAddress,
+		log:     logger.Log.With(zap.String("component", "accrual")),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *Supervisor) Start(ctx context.Context) error {
+	if _, err := os.Stat(s.path); err != nil {
+		return fmt.Errorf("accrual binary error: %w", err)
+	}
+	s.log.Info("Accrual binary path", zap.String("path", s.path))
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	go s.loop(ctx)
+
+	return nil
+}
+
+// WaitHealthy blocks until accrual accepts connections
+func (s *Supervisor) WaitHealthy(ctx context.Context) error {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+
+	for {
+		conn, err := net.DialTimeout("tcp", s.address, healthInterval)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop terminates accrual gracefully and waits for supervisor loop to exit
+func (s *Supervisor) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+func (s *Supervisor) loop(ctx context.Context) {
+	defer close(s.done)
+
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		err := s.run(ctx)
+
+		if ctx.Err() != nil {
+			s.log.Info("Accrual process stopped")
+			return
+		}
+
+		if time.Since(started) > stableRun {
+			backoff = minBackoff
+		}
+		s.log.Error("Accrual process exited, restarting", zap.Error(err), zap.Duration("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (s *Supervisor) run(ctx context.Context) error {
+	cmd := exec.Command(s.path, "-a", s.address)
+	cmd.Env = []string{}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s.log.Info("Accrual process started", zap.Int("pid", cmd.Process.Pid))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go s.forward(stdout, "stdout", &wg)
+	go s.forward(stderr, "stderr", &wg)
+
+	exited := make(chan error, 1)
+	go func() {
+		wg.Wait()
+		exited <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-exited:
+		return err
+	case <-ctx.Done():
+		return s.terminate(cmd, exited)
+	}
+}
+
+func (s *Supervisor) terminate(cmd *exec.Cmd, exited chan error) error {
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		_ = cmd.Process.Kill()
+	}
+
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(terminateWindow):
+		s.log.Warn("Accrual process did not terminate in time, killing")
+		_ = cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+func (s *Supervisor) forward(r io.Reader, stream string, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		s.log.Info(scanner.Text(), zap.String("stream", stream))
+	}
+}
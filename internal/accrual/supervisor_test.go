@@ -0,0 +1,42 @@
+package accrual
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSupervisorRestartsCrashedProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script fixture")
+	}
+
+	dir := t.TempDir()
+	starts := filepath.Join(dir, "starts")
+	bin := filepath.Join(dir, "accrual")
+	script := "#!/bin/sh\necho started >> " + starts + "\nexit 1\n"
+	require.NoError(t, os.WriteFile(bin, []byte(script), 0o755))
+
+	s := &Supervisor{
+		path:    bin,
+		address: "localhost:0",
+		log:     zap.NewNop(),
+		done:    make(chan struct{}),
+	}
+	require.NoError(t, s.Start(context.Background()))
+
+	assert.Eventually(t, func() bool {
+		data, _ := os.ReadFile(starts)
+		return strings.Count(string(data), "started") >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	s.Stop()
+}
@@ -3,37 +3,97 @@ package client
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/morzisorn/gofermart/internal/models"
 	"resty.dev/v3"
 )
 
+const (
+	defaultRetryAfter = 60 * time.Second
+	minRetryAfter     = time.Second // Zero or past Retry-After must not make workers hammer throttling accrual
+)
+
+var rateLimitBody = regexp.MustCompile(`No more than (\d+) requests per minute`)
+
 type HTTPClient struct {
 	BaseURL string
 	Client  *resty.Client
 }
 
+// ThrottleError is returned when the accrual system responds with 429 Too Many Requests.
+type ThrottleError struct {
+	RetryAfter time.Duration
+	Limit      int // Requests per minute reported by accrual, 0 if unknown
+}
+
+func (e *ThrottleError) Error() string {
+	return fmt.Sprintf("accrual rate limit exceeded (limit %d rpm), retry after %s", e.Limit, e.RetryAfter)
+}
+
 func (c *HTTPClient) CalculateBonuses(ctx context.Context, number string) (*models.LoyaltyOrder, error) {
 	base := &url.URL{
 		Scheme: "http",
-		Host: c.BaseURL,
-		Path: "api/orders/",
+		Host:   c.BaseURL,
+		Path:   "api/orders/",
 	}
 
 	url := base.ResolveReference(&url.URL{Path: number})
 
 	var order models.LoyaltyOrder
 	resp, err := c.Client.R().
+		SetContext(ctx).
 		SetResult(&order).
 		Get(url.String())
 
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode() != 200 {
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+		return &order, nil
+	case http.StatusNoContent:
+		return &models.LoyaltyOrder{
+			Order:  number,
+			Status: models.LoyaltyStatusNOTREGISTERED,
+		}, nil
+	case http.StatusTooManyRequests:
+		return nil, newThrottleError(resp.Header().Get("Retry-After"), resp.String(), time.Now())
+	default:
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode())
 	}
+}
+
+func newThrottleError(retryAfter, body string, now time.Time) *ThrottleError {
+	e := &ThrottleError{
+		RetryAfter: parseRetryAfter(retryAfter, now),
+	}
+
+	if m := rateLimitBody.FindStringSubmatch(body); m != nil {
+		e.Limit, _ = strconv.Atoi(m[1])
+	}
+
+	return e
+}
+
+// Retry-After is either delay in seconds or HTTP-date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return defaultRetryAfter
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return max(time.Duration(seconds)*time.Second, minRetryAfter)
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), minRetryAfter)
+	}
 
-	return &order, nil
+	return defaultRetryAfter
 }
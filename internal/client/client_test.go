@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"resty.dev/v3"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 4, 16, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter("", now))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter("soon", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, minRetryAfter, parseRetryAfter("0", now))
+	assert.Equal(t, minRetryAfter, parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+}
+
+func TestCalculateBonusesThrottled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte("No more than 10 requests per minute allowed"))
+	}))
+	defer srv.Close()
+
+	c := &HTTPClient{
+		BaseURL: strings.TrimPrefix(srv.URL, "http://"),
+		Client:  resty.New(),
+	}
+
+	_, err := c.CalculateBonuses(context.Background(), "79927398713")
+
+	var throttle *ThrottleError
+	require.True(t, errors.As(err, &throttle))
+	assert.Equal(t, 60*time.Second, throttle.RetryAfter)
+	assert.Equal(t, 10, throttle.Limit)
+}
+
+func TestCalculateBonusesNotRegistered(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := &HTTPClient{
+		BaseURL: strings.TrimPrefix(srv.URL, "http://"),
+		Client:  resty.New(),
+	}
+
+	lo, err := c.CalculateBonuses(context.Background(), "79927398713")
+	require.NoError(t, err)
+	assert.Equal(t, models.LoyaltyStatusNOTREGISTERED, lo.Status)
+	assert.Equal(t, "79927398713", lo.Order)
+}
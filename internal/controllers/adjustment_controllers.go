@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/internal/services/adjustments"
+)
+
+type AdjustmentController struct {
+	service *adjustments.AdjustmentService
+}
+
+func NewAdjustmentController(s *adjustments.AdjustmentService) *AdjustmentController {
+	return &AdjustmentController{service: s}
+}
+
+// GetUserAdjustments shows user own balance adjustments without operator logins
+func (ac *AdjustmentController) GetUserAdjustments(c *gin.Context) {
+	adjs, err := ac.service.GetUserAdjustments(c.Request.Context(), c.GetString("login"))
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	for i := range *adjs {
+		(*adjs)[i].UserLogin = ""
+		(*adjs)[i].OperatorLogin = ""
+	}
+
+	c.JSON(http.StatusOK, adjs)
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/morzisorn/gofermart/internal/services/adjustments"
+	"github.com/morzisorn/gofermart/internal/services/ledger"
+	"github.com/morzisorn/gofermart/internal/services/orders"
+	"github.com/morzisorn/gofermart/internal/services/users"
+)
+
+// AdminController lets operators look up data of any user by :login path parameter
+type AdminController struct {
+	users       *users.UserService
+	orders      *orders.OrderService
+	adjustments *adjustments.AdjustmentService
+	ledger      *ledger.LedgerService
+}
+
+func NewAdminController(us *users.UserService, os *orders.OrderService, as *adjustments.AdjustmentService, ls *ledger.LedgerService) *AdminController {
+	return &AdminController{
+		users:       us,
+		orders:      os,
+		adjustments: as,
+		ledger:      ls,
+	}
+}
+
+func (ac *AdminController) GetUserBalance(c *gin.Context) {
+	balance, err := ac.users.GetBalance(c.Request.Context(), &models.User{
+		Login: c.Param("login"),
+	})
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
+func (ac *AdminController) GetUserOrders(c *gin.Context) {
+	login, ok := ac.userLogin(c)
+	if !ok {
+		return
+	}
+
+	ord, err := ac.orders.GetUserOrdersProcessing(c.Request.Context(), login)
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, ord)
+}
+
+// GetParkedOrders lists orders taken out of processing: unregistered in accrual or stuck
+func (ac *AdminController) GetParkedOrders(c *gin.Context) {
+	ord, err := ac.orders.GetParkedOrders(c.Request.Context())
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, ord)
+}
+
+func (ac *AdminController) GetUserWithdrawals(c *gin.Context) {
+	login, ok := ac.userLogin(c)
+	if !ok {
+		return
+	}
+
+	withdrawals, err := ac.orders.GetUserWithdrawals(c.Request.Context(), login)
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, withdrawals)
+}
+
+func (ac *AdminController) GetUserAdjustments(c *gin.Context) {
+	login, ok := ac.userLogin(c)
+	if !ok {
+		return
+	}
+
+	adjs, err := ac.adjustments.GetUserAdjustments(c.Request.Context(), login)
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, adjs)
+}
+
+// GetUserLedger lists ledger entries that make up balance of the user
+func (ac *AdminController) GetUserLedger(c *gin.Context) {
+	login, ok := ac.userLogin(c)
+	if !ok {
+		return
+	}
+
+	entries, err := ac.ledger.GetUserEntries(c.Request.Context(), login)
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// AdjustBalance applies signed amount to balance of user from path, operator is the caller
+func (ac *AdminController) AdjustBalance(c *gin.Context) {
+	var req models.BalanceAdjustment
+	if err := c.BindJSON(&req); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	adj, err := ac.adjustments.Adjust(c.Request.Context(), c.GetString("login"), c.Param("login"), &req)
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, adj)
+}
+
+func (ac *AdminController) SetUserRole(c *gin.Context) {
+	var req models.SetRole
+	if err := c.BindJSON(&req); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := ac.users.SetUserRole(c.Request.Context(), c.Param("login"), req.Role)
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// userLogin checks that user from path exists, so unknown login is 404 rather than empty list
+func (ac *AdminController) userLogin(c *gin.Context) (string, bool) {
+	user, err := ac.users.GetUser(c.Request.Context(), &models.User{Login: c.Param("login")})
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return "", false
+	}
+	return user.Login, true
+}
@@ -1,34 +1,90 @@
 package controllers
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/keyring"
+	"github.com/morzisorn/gofermart/internal/logger"
+	"github.com/morzisorn/gofermart/internal/models"
+	"go.uber.org/zap"
 )
 
 var (
 	ErrIncorrectAuthToken = errors.New("incorrect authorization token")
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// AuthMiddleware accepts only tokens of sessions that were not revoked by logout.
+// Token is taken from Authorization header or, in cookie mode, from access_token cookie.
+// Cookie-authenticated state-changing requests must pass CSRF check.
+func AuthMiddleware(sessions SessionChecker, cnfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.Request.Header.Get("Authorization")
+		authHeader, fromCookie := accessToken(c, cnfg)
 		if authHeader == "" {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
+		if fromCookie && !validCSRF(c) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
 		claims, err := validateToken(authHeader)
 		if err != nil {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
-		c.Set("login", claims["login"].(string))
+
+		// Tokens without session id were issued before sessions and can not be revoked, they are rejected
+		login, _ := claims["login"].(string)
+		sessionID, _ := claims["sid"].(string)
+		if login == "" || sessionID == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		active, err := sessions.IsSessionActive(c.Request.Context(), sessionID)
+		if err != nil {
+			logger.Log.Error("Failed to check session", zap.String("session", sessionID), zap.Error(err))
+			c.AbortWithStatus(statusFromError(err))
+			return
+		}
+		if !active {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		// Tokens issued before roles were introduced belong to regular users
+		role, _ := claims["role"].(string)
+		if role == "" {
+			role = models.RoleUser
+		}
+
+		c.Set("login", login)
+		c.Set("role", role)
+		c.Set("session", sessionID)
+		c.Next()
+	}
+}
+
+// RequireRole must be used after AuthMiddleware, it lets through only listed roles
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, c.GetString("role")) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
 		c.Next()
 	}
 }
@@ -43,20 +99,10 @@ func validateToken(authHeader string) (jwt.MapClaims, error) {
 		return nil, ErrIncorrectAuthToken
 	}
 
-	token, err := jwt.Parse(authHeaderParts[1], func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("incorrect signing method: %v", token.Method)
-		}
-		cnfg := config.GetConfig()
-		return []byte(cnfg.SecretKey), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
-	}
+	return keyring.GetKeyring().Parse(authHeaderParts[1])
+}
 
-	return nil, ErrIncorrectAuthToken
+// JWKS publishes public keys so other services can verify tokens without shared secret
+func JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, keyring.GetKeyring().JWKS())
 }
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/keyring"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		role string
+		want int
+	}{
+		{role: models.RoleUser, want: http.StatusForbidden},
+		{role: models.RoleSupport, want: http.StatusOK},
+		{role: models.RoleAdmin, want: http.StatusOK},
+		{role: "", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			mux := gin.New()
+			mux.GET("/api/admin",
+				func(c *gin.Context) { c.Set("role", tt.role) },
+				RequireRole(models.RoleSupport, models.RoleAdmin),
+				func(c *gin.Context) { c.Status(http.StatusOK) },
+			)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin", nil))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+type stubSessions map[string]bool
+
+func (s stubSessions) IsSessionActive(_ context.Context, id string) (bool, error) {
+	return s[id], nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	require.NoError(t, keyring.Init(&config.Config{JWTAlgorithm: "HS256", SecretKey: "test"}))
+
+	sign := func(claims jwt.MapClaims) string {
+		claims["exp"] = time.Now().Add(time.Minute).Unix()
+		token, err := keyring.GetKeyring().Sign(claims)
+		require.NoError(t, err)
+		return "Bearer " + token
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{name: "active session", token: sign(jwt.MapClaims{"login": "user", "sid": "active"}), want: http.StatusOK},
+		{name: "logged out session", token: sign(jwt.MapClaims{"login": "user", "sid": "revoked"}), want: http.StatusUnauthorized},
+		{name: "token before sessions", token: sign(jwt.MapClaims{"login": "user"}), want: http.StatusUnauthorized},
+		{name: "no login", token: sign(jwt.MapClaims{"sid": "active"}), want: http.StatusUnauthorized},
+		{name: "no token", want: http.StatusUnauthorized},
+	}
+
+	sessions := stubSessions{"active": true, "revoked": false}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := gin.New()
+			mux.GET("/api/user/balance",
+				AuthMiddleware(sessions, &config.Config{}),
+				func(c *gin.Context) { c.Status(http.StatusOK) },
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/balance", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
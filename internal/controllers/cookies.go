@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/models"
+)
+
+const (
+	accessTokenCookie  = "access_token"
+	refreshTokenCookie = "refresh_token"
+	csrfTokenCookie    = "csrf_token"
+	csrfTokenHeader    = "X-CSRF-Token"
+
+	refreshTokenPath = "/api/user/token/refresh"
+)
+
+// setAuthCookies stores tokens in HttpOnly cookies, so scripts on the page can not read them.
+// CSRF token is readable by the frontend, it must echo it in X-CSRF-Token header.
+func setAuthCookies(c *gin.Context, cnfg *config.Config, tokens *models.Tokens) error {
+	csrf, err := newCSRFToken()
+	if err != nil {
+		return err
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(accessTokenCookie, tokens.AccessToken, tokens.ExpiresIn, "/", "", cnfg.CookieSecure, true)
+	c.SetCookie(refreshTokenCookie, tokens.RefreshToken, cnfg.RefreshTokenTTL, refreshTokenPath, "", cnfg.CookieSecure, true)
+	c.SetCookie(csrfTokenCookie, csrf, cnfg.RefreshTokenTTL, "/", "", cnfg.CookieSecure, false)
+
+	return nil
+}
+
+func clearAuthCookies(c *gin.Context, cnfg *config.Config) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(accessTokenCookie, "", -1, "/", "", cnfg.CookieSecure, true)
+	c.SetCookie(refreshTokenCookie, "", -1, refreshTokenPath, "", cnfg.CookieSecure, true)
+	c.SetCookie(csrfTokenCookie, "", -1, "/", "", cnfg.CookieSecure, false)
+}
+
+// accessToken returns bearer token from Authorization header or, in cookie mode, from cookie.
+// Header takes precedence so API clients are not affected by stale browser cookies.
+func accessToken(c *gin.Context, cnfg *config.Config) (token string, fromCookie bool) {
+	if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+		return authHeader, false
+	}
+
+	if !cnfg.AuthCookies {
+		return "", false
+	}
+
+	token, err := c.Cookie(accessTokenCookie)
+	if err != nil || token == "" {
+		return "", false
+	}
+	return "Bearer " + token, true
+}
+
+// validCSRF implements double-submit check: header must match cookie.
+// Safe methods do not change state and are not checked.
+func validCSRF(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookie, err := c.Cookie(csrfTokenCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+
+	header := c.GetHeader(csrfTokenHeader)
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		cookies    bool
+		header     string
+		cookie     string
+		wantToken  string
+		fromCookie bool
+	}{
+		{name: "header", cookies: true, header: "Bearer h", cookie: "c", wantToken: "Bearer h"},
+		{name: "cookie", cookies: true, cookie: "c", wantToken: "Bearer c", fromCookie: true},
+		{name: "cookie mode disabled", cookies: false, cookie: "c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("Authorization", tt.header)
+			}
+			c.Request.AddCookie(&http.Cookie{Name: accessTokenCookie, Value: tt.cookie})
+
+			token, fromCookie := accessToken(c, &config.Config{AuthCookies: tt.cookies})
+			assert.Equal(t, tt.wantToken, token)
+			assert.Equal(t, tt.fromCookie, fromCookie)
+		})
+	}
+}
+
+func TestValidCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		method string
+		cookie string
+		header string
+		want   bool
+	}{
+		{name: "safe method", method: http.MethodGet, want: true},
+		{name: "matching", method: http.MethodPost, cookie: "token", header: "token", want: true},
+		{name: "missing header", method: http.MethodPost, cookie: "token"},
+		{name: "mismatch", method: http.MethodPost, cookie: "token", header: "other"},
+		{name: "missing cookie", method: http.MethodPost, header: "token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(tt.method, "/", nil)
+			if tt.cookie != "" {
+				c.Request.AddCookie(&http.Cookie{Name: csrfTokenCookie, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				c.Request.Header.Set(csrfTokenHeader, tt.header)
+			}
+
+			assert.Equal(t, tt.want, validCSRF(c))
+		})
+	}
+}
+
+func TestSetAuthCookies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	cnfg := &config.Config{CookieSecure: true, RefreshTokenTTL: 3600}
+	err := setAuthCookies(c, cnfg, &models.Tokens{AccessToken: "a", RefreshToken: "r", ExpiresIn: 60})
+	assert.NoError(t, err)
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+
+	for _, name := range []string{accessTokenCookie, refreshTokenCookie, csrfTokenCookie} {
+		cookie, ok := cookies[name]
+		if assert.True(t, ok, name) {
+			assert.True(t, cookie.Secure, name)
+			assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite, name)
+		}
+	}
+	assert.True(t, cookies[accessTokenCookie].HttpOnly)
+	assert.True(t, cookies[refreshTokenCookie].HttpOnly)
+	assert.False(t, cookies[csrfTokenCookie].HttpOnly, "frontend must read CSRF token")
+	assert.Equal(t, refreshTokenPath, cookies[refreshTokenCookie].Path)
+}
+
+func TestWriteTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		cookies    bool
+		wantTokens bool
+	}{
+		{name: "header mode", cookies: false, wantTokens: true},
+		{name: "cookie mode", cookies: true, wantTokens: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			uc := NewUserController(nil, &config.Config{AuthCookies: tt.cookies, RefreshTokenTTL: 3600})
+			uc.writeTokens(c, &models.Tokens{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 60})
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), `"expires_in":60`)
+			assert.Equal(t, tt.wantTokens, strings.Contains(w.Body.String(), "refresh"), w.Body.String())
+			assert.Equal(t, tt.wantTokens, strings.Contains(w.Body.String(), "access"), w.Body.String())
+			assert.Equal(t, tt.wantTokens, w.Header().Get("Authorization") != "")
+			assert.Equal(t, tt.cookies, len(w.Result().Cookies()) > 0)
+		})
+	}
+}
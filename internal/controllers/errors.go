@@ -1,15 +1,36 @@
 package controllers
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
+	"github.com/gin-gonic/gin"
 	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/services/loginguard"
+	"github.com/morzisorn/gofermart/internal/validation"
 )
 
+// writeError responds with list of violated fields for validation errors,
+// adds Retry-After to lockouts and responds with plain text for the rest
+func writeError(c *gin.Context, err error) {
+	var verr *validation.Error
+	if errors.As(err, &verr) {
+		c.JSON(http.StatusBadRequest, verr)
+		return
+	}
+
+	var locked *loginguard.LockedError
+	if errors.As(err, &locked) {
+		c.Header("Retry-After", locked.RetryAfterSeconds())
+	}
+
+	c.String(statusFromError(err), err.Error())
+}
+
 func statusFromError(err error) int {
 	switch {
-	case errors.Is(err, errs.ErrIncorrectNumber):
+	case errors.Is(err, errs.ErrIncorrectNumber), errors.Is(err, errs.ErrIncorrectSum):
 		return http.StatusUnprocessableEntity
 	case errors.Is(err, errs.ErrOrderAlreadyExist):
 		return http.StatusOK
@@ -21,8 +42,25 @@ func statusFromError(err error) int {
 		return http.StatusPaymentRequired
 	case errors.Is(err, errs.ErrUserAlreadyRegistered):
 		return http.StatusConflict
-	case errors.Is(err, errs.ErrIncorrectCredentials):
+	case errors.Is(err, errs.ErrIncorrectCredentials), errors.Is(err, errs.ErrInvalidRefreshToken):
 		return http.StatusUnauthorized
+	case errors.Is(err, errs.ErrValidation), errors.Is(err, errs.ErrUnknownRole),
+		errors.Is(err, errs.ErrInvalidAdjustment):
+		return http.StatusBadRequest
+	case errors.Is(err, errs.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errs.ErrTooManyLoginAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errs.ErrIncorrectPassword):
+		return http.StatusForbidden
+	case errors.Is(err, errs.ErrPositiveBalance):
+		return http.StatusConflict
+	case errors.Is(err, errs.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errs.ErrIdempotencyKeyInProgress):
+		return http.StatusConflict
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
 	default:
 		return http.StatusInternalServerError
 	}
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/internal/models"
+)
+
+type ProcessingStatusReporter interface {
+	Status() models.ProcessingStatus
+}
+
+// Health answers 200 while HTTP is served, failing order processing only marks status degraded
+func Health(processing ProcessingStatusReporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		health := models.Health{
+			Status:     models.HealthOK,
+			Processing: processing.Status(),
+		}
+		if health.Processing.Failures > 0 {
+			health.Status = models.HealthDegraded
+		}
+
+		c.JSON(http.StatusOK, health)
+	}
+}
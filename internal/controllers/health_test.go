@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubProcessingStatus models.ProcessingStatus
+
+func (s stubProcessingStatus) Status() models.ProcessingStatus {
+	return models.ProcessingStatus(s)
+}
+
+func TestHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		processing models.ProcessingStatus
+		want       string
+	}{
+		{"processing ok", models.ProcessingStatus{State: models.BreakerClosed}, models.HealthOK},
+		{"processing failing", models.ProcessingStatus{State: models.BreakerClosed, Failures: 1}, models.HealthDegraded},
+		{"circuit open", models.ProcessingStatus{State: models.BreakerOpen, Failures: 5}, models.HealthDegraded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := gin.New()
+			mux.GET("/health", Health(stubProcessingStatus(tt.processing)))
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var health models.Health
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+			assert.Equal(t, tt.want, health.Status)
+			assert.Equal(t, tt.processing.State, health.Processing.State)
+		})
+	}
+}
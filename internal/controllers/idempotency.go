@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/internal/logger"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/morzisorn/gofermart/internal/services/idempotency"
+	"go.uber.org/zap"
+)
+
+const maxIdempotencyKeyLength = 255
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays stored response for a repeated Idempotency-Key.
+// Must be used after AuthMiddleware, keys are scoped by user login.
+func Idempotency(s *idempotency.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.String(http.StatusBadRequest, "Idempotency-Key is too long")
+			c.Abort()
+			return
+		}
+
+		body, err := c.GetRawData()
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		// Actual path, not route pattern: the same key must not replay response for another path parameter
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+
+		login := c.GetString("login")
+
+		stored, err := s.Begin(c.Request.Context(), login, key, hash.Sum(nil))
+		if err != nil {
+			c.String(statusFromError(err), err.Error())
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Result is saved even if client has gone, otherwise its retry would run again
+		ctx := context.WithoutCancel(c.Request.Context())
+
+		// Key is freed after 5xx or panic so the client can retry the request
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := s.Release(ctx, login, key); err != nil {
+				logger.Log.Error("Failed to release idempotency key", zap.Error(err))
+			}
+		}()
+
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+		completed = true
+
+		err = s.Complete(ctx, login, key, &models.IdempotentResponse{
+			StatusCode:  recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			logger.Log.Error("Failed to save idempotent response", zap.Error(err))
+		}
+	}
+}
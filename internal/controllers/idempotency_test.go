@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/morzisorn/gofermart/internal/services/idempotency"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]*models.IdempotentResponse
+}
+
+func (s *memoryIdempotencyStore) ClaimIdempotencyKey(_ context.Context, login, key string, requestHash []byte, _, _ int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[login+"/"+key]; ok {
+		return false, nil
+	}
+	s.keys[login+"/"+key] = &models.IdempotentResponse{RequestHash: requestHash}
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) GetIdempotencyKey(_ context.Context, login, key string) (*models.IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[login+"/"+key], nil
+}
+
+func (s *memoryIdempotencyStore) CompleteIdempotencyKey(_ context.Context, login, key string, resp *models.IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.keys[login+"/"+key]
+	stored.Completed = true
+	stored.StatusCode = resp.StatusCode
+	stored.ContentType = resp.ContentType
+	stored.Body = append([]byte(nil), resp.Body...)
+	return nil
+}
+
+func (s *memoryIdempotencyStore) ReleaseIdempotencyKey(_ context.Context, login, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, login+"/"+key)
+	return nil
+}
+
+func requestHash(body string) []byte {
+	hash := sha256.Sum256([]byte(http.MethodPost + " /withdraw\n" + body))
+	return hash[:]
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		stored     *models.IdempotentResponse
+		handler    gin.HandlerFunc
+		wantStatus int
+		wantBody   string
+		wantCalls  int
+		replayed   bool
+		wantKept   bool
+	}{
+		{
+			name:       "first request",
+			handler:    func(c *gin.Context) { c.String(http.StatusOK, "done") },
+			wantStatus: http.StatusOK,
+			wantBody:   "done",
+			wantCalls:  1,
+			wantKept:   true,
+		},
+		{
+			name: "replay",
+			stored: &models.IdempotentResponse{
+				RequestHash: requestHash("body"),
+				Completed:   true,
+				StatusCode:  http.StatusOK,
+				ContentType: "text/plain",
+				Body:        []byte("done"),
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "done",
+			replayed:   true,
+			wantKept:   true,
+		},
+		{
+			name: "key reused with different body",
+			stored: &models.IdempotentResponse{
+				RequestHash: requestHash("other"),
+				Completed:   true,
+				StatusCode:  http.StatusOK,
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantKept:   true,
+		},
+		{
+			name:       "in progress",
+			stored:     &models.IdempotentResponse{RequestHash: requestHash("body")},
+			wantStatus: http.StatusConflict,
+			wantKept:   true,
+		},
+		{
+			name:       "released on 5xx",
+			handler:    func(c *gin.Context) { c.String(http.StatusInternalServerError, "failed") },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "failed",
+			wantCalls:  1,
+		},
+		{
+			name:       "released on panic",
+			handler:    func(c *gin.Context) { panic("handler failed") },
+			wantStatus: http.StatusInternalServerError,
+			wantCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryIdempotencyStore{keys: map[string]*models.IdempotentResponse{}}
+			if tt.stored != nil {
+				store.keys["user/key"] = tt.stored
+			}
+			s := idempotency.NewIdempotencyService(store, &config.Config{IdempotencyWindow: 60, IdempotencyLease: 60})
+
+			calls := 0
+			r := gin.New()
+			r.Use(gin.Recovery(), func(c *gin.Context) { c.Set("login", "user") })
+			r.POST("/withdraw", Idempotency(s), func(c *gin.Context) {
+				calls++
+				tt.handler(c)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/withdraw", strings.NewReader("body"))
+			req.Header.Set("Idempotency-Key", "key")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.Equal(t, tt.replayed, w.Header().Get("Idempotent-Replayed") == "true")
+
+			_, kept := store.keys["user/key"]
+			assert.Equal(t, tt.wantKept, kept)
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package controllers
 
 import (
+	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/config"
 )
 
 func RequireContentType(expected string) gin.HandlerFunc {
@@ -18,3 +21,29 @@ func RequireContentType(expected string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequestTimeout sets deadline on request context so services and repositories stop work
+// when it expires or client disconnects. Route keys are "METHOD /full/path".
+func RequestTimeout(cnfg *config.Config) gin.HandlerFunc {
+	def := time.Duration(cnfg.RequestTimeout) * time.Second
+
+	routes := make(map[string]time.Duration, len(cnfg.RouteTimeouts))
+	for route, seconds := range cnfg.RouteTimeouts {
+		routes[route] = time.Duration(seconds) * time.Second
+	}
+
+	return func(c *gin.Context) {
+		timeout, ok := routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			timeout = def
+		}
+
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cnfg := &config.Config{
+		RequestTimeout: 5,
+		RouteTimeouts:  map[string]int{"POST /api/user/balance/withdraw": 10},
+	}
+
+	deadlines := make(map[string]time.Duration)
+	handler := func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		assert.True(t, ok)
+		deadlines[c.Request.Method+" "+c.FullPath()] = time.Until(deadline).Round(time.Second)
+	}
+
+	mux := gin.New()
+	mux.Use(RequestTimeout(cnfg))
+	mux.GET("/api/user/balance", handler)
+	mux.POST("/api/user/balance/withdraw", handler)
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/user/balance", nil),
+		httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", nil),
+	} {
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	assert.Equal(t, 5*time.Second, deadlines["GET /api/user/balance"])
+	assert.Equal(t, 10*time.Second, deadlines["POST /api/user/balance/withdraw"])
+}
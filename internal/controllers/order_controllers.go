@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -26,7 +25,7 @@ func (oc *OrderController) UploadOrder(c *gin.Context) {
 		return
 	}
 
-	err = oc.service.UploadOrder(context.Background(), login, string(number))
+	err = oc.service.UploadOrder(c.Request.Context(), login, string(number))
 	if err != nil {
 		c.String(statusFromError(err), err.Error())
 	}
@@ -36,7 +35,7 @@ func (oc *OrderController) UploadOrder(c *gin.Context) {
 func (oc *OrderController) GetUserOrders(c *gin.Context) {
 	login := c.GetString("login")
 
-	ord, err := oc.service.GetUserOrders(context.Background(), login)
+	ord, err := oc.service.GetUserOrders(c.Request.Context(), login)
 	if err != nil {
 		c.String(statusFromError(err), err.Error())
 	}
@@ -47,7 +46,7 @@ func (oc *OrderController) GetUserOrders(c *gin.Context) {
 func (oc *OrderController) GetUserWithdrawals(c *gin.Context) {
 	login := c.GetString("login")
 
-	withdrawals, err := oc.service.GetUserWithdrawals(context.Background(), login)
+	withdrawals, err := oc.service.GetUserWithdrawals(c.Request.Context(), login)
 	if err != nil {
 		c.String(statusFromError(err), err.Error())
 	}
@@ -64,7 +63,7 @@ func (oc *OrderController) Withdraw(c *gin.Context) {
 		return
 	}
 
-	err := oc.service.Withdraw(context.Background(), login, &w)
+	err := oc.service.Withdraw(c.Request.Context(), login, &w)
 	if err != nil {
 		c.String(statusFromError(err), err.Error())
 	}
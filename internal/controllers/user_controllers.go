@@ -1,21 +1,26 @@
 package controllers
 
 import (
-	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/morzisorn/gofermart/config"
 	"github.com/morzisorn/gofermart/internal/models"
 	"github.com/morzisorn/gofermart/internal/services/users"
 )
 
 type UserController struct {
 	service *users.UserService
+	cnfg    *config.Config
 }
 
-func NewUserController(s *users.UserService) *UserController {
-	return &UserController{service: s}
+func NewUserController(s *users.UserService, cnfg *config.Config) *UserController {
+	return &UserController{
+		service: s,
+		cnfg:    cnfg,
+	}
 }
 
 func (uc *UserController) RegisterUser(c *gin.Context) {
@@ -25,13 +30,13 @@ func (uc *UserController) RegisterUser(c *gin.Context) {
 		return
 	}
 
-	token, err := uc.service.RegisterUser(context.Background(), &user)
+	tokens, err := uc.service.RegisterUser(c.Request.Context(), &user)
 	if err != nil {
-		c.String(statusFromError(err), err.Error())
+		writeError(c, err)
+		return
 	}
 
-	c.Writer.Header().Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	c.Status(http.StatusOK)
+	uc.writeTokens(c, tokens)
 }
 
 func (uc *UserController) Login(c *gin.Context) {
@@ -41,19 +46,112 @@ func (uc *UserController) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := uc.service.LoginUser(context.Background(), &user)
+	tokens, err := uc.service.LoginUser(c.Request.Context(), &user, c.ClientIP())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	uc.writeTokens(c, tokens)
+}
+
+func (uc *UserController) RefreshToken(c *gin.Context) {
+	var req models.RefreshRequest
+
+	cookie, err := c.Cookie(refreshTokenCookie)
+	switch {
+	case uc.cnfg.AuthCookies && err == nil && cookie != "":
+		if !validCSRF(c) {
+			c.Status(http.StatusForbidden)
+			return
+		}
+		req.RefreshToken = cookie
+	default:
+		if err := c.BindJSON(&req); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	tokens, err := uc.service.RefreshSession(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	uc.writeTokens(c, tokens)
+}
+
+func (uc *UserController) Logout(c *gin.Context) {
+	err := uc.service.Logout(c.Request.Context(), c.GetString("session"))
 	if err != nil {
 		c.String(statusFromError(err), err.Error())
+		return
 	}
 
-	c.Writer.Header().Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	if uc.cnfg.AuthCookies {
+		clearAuthCookies(c, uc.cnfg)
+	}
 	c.Status(http.StatusOK)
 }
 
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	var req models.ChangePassword
+	if err := c.BindJSON(&req); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := uc.service.ChangePassword(c.Request.Context(), c.GetString("login"), c.GetString("session"), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// DeleteUser closes account of the current user. Positive balance is kept
+// unless the request explicitly agrees to forfeit it with ?forfeit=true.
+func (uc *UserController) DeleteUser(c *gin.Context) {
+	forfeit, err := strconv.ParseBool(c.DefaultQuery("forfeit", "false"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "forfeit must be a boolean")
+		return
+	}
+
+	err = uc.service.DeleteUser(c.Request.Context(), c.GetString("login"), forfeit)
+	if err != nil {
+		c.String(statusFromError(err), err.Error())
+		return
+	}
+
+	if uc.cnfg.AuthCookies {
+		clearAuthCookies(c, uc.cnfg)
+	}
+	c.Status(http.StatusOK)
+}
+
+// writeTokens keeps access token in Authorization header and tokens in body for API clients.
+// In cookie mode tokens are only set as HttpOnly cookies, scripts on the page must not read them.
+func (uc *UserController) writeTokens(c *gin.Context, tokens *models.Tokens) {
+	if uc.cnfg.AuthCookies {
+		if err := setAuthCookies(c, uc.cnfg, tokens); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, models.Tokens{ExpiresIn: tokens.ExpiresIn})
+		return
+	}
+
+	c.Writer.Header().Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+	c.JSON(http.StatusOK, tokens)
+}
+
 func (uc *UserController) GetBalance(c *gin.Context) {
 	login := c.GetString("login")
 
-	balance, err := uc.service.GetBalance(context.Background(), &models.User{
+	balance, err := uc.service.GetBalance(c.Request.Context(), &models.User{
 		Login: login,
 	})
 	if err != nil {
@@ -5,16 +5,31 @@ import "errors"
 var (
 	//Order errors
 	ErrIncorrectNumber         = errors.New("number validation failed")
+	ErrIncorrectSum            = errors.New("sum must be positive")
 	ErrOrderAlreadyExist       = errors.New("order number is already exist")
 	ErrOrderBelongsAnotherUser = errors.New("belongs to another user")
 	ErrNoData                  = errors.New("no data")
+	ErrIllegalOrderTransition  = errors.New("illegal order status transition")
 	
 	//User errors
 	ErrInsufficientBalance     = errors.New("insufficient balance")
 	ErrUserNotFound          = errors.New("user not found")
 	ErrUserAlreadyRegistered = errors.New("user is already registered")
 	ErrIncorrectCredentials  = errors.New("incorrect login or password")
+	ErrInvalidRefreshToken   = errors.New("invalid refresh token")
+	ErrValidation            = errors.New("validation error")
+	ErrTooManyLoginAttempts  = errors.New("too many failed login attempts")
+	ErrUnknownRole           = errors.New("unknown role")
+	ErrIncorrectPassword     = errors.New("incorrect current password")
+	ErrPositiveBalance       = errors.New("account has positive balance, it must be forfeited to delete account")
+
+	//Adjustment errors
+	ErrInvalidAdjustment = errors.New("adjustment requires non-zero amount, known reason code and note")
 	
+	//Idempotency errors
+	ErrIdempotencyKeyReused     = errors.New("idempotency key is already used for another request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is in progress")
+
 	//Other errors
 	ErrInternalServerError   = errors.New("internal server error")
 )
@@ -6,8 +6,10 @@ import (
 	"github.com/morzisorn/gofermart/config"
 )
 
+// GetHash is the legacy password hash. It is only used to verify passwords
+// stored before argon2id and upgrade them on login.
 func GetHash(body []byte) [32]byte {
 	cnfg := config.GetConfig()
-	str := append(body, []byte(cnfg.SecretKey)...)
+	str := append(body, []byte(cnfg.PasswordPepper)...)
 	return sha256.Sum256(str)
 }
\ No newline at end of file
@@ -0,0 +1,134 @@
+package hash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Params of argon2id. They are stored with every hash, so changing them
+// only affects new hashes and triggers rehash of old ones on login.
+type Params struct {
+	Memory      uint32 //KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+var DefaultParams = Params{
+	Memory:      64 * 1024,
+	Iterations:  1,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+const legacyHashLength = 32
+
+// HashPassword returns argon2id hash encoded as
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>
+func HashPassword(password string) ([]byte, error) {
+	return hashPassword(password, DefaultParams)
+}
+
+func hashPassword(password string, p Params) ([]byte, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate salt error: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return []byte(encoded), nil
+}
+
+// VerifyPassword checks password against stored hash. needsRehash is true for legacy
+// SHA-256 hashes and for argon2id hashes made with parameters other than DefaultParams.
+func VerifyPassword(password string, stored []byte) (ok bool, needsRehash bool, err error) {
+	if !bytes.HasPrefix(stored, []byte("$argon2id$")) {
+		if len(stored) != legacyHashLength {
+			return false, false, ErrUnknownHashFormat
+		}
+
+		legacy := GetHash([]byte(password))
+		return subtle.ConstantTimeCompare(legacy[:], stored) == 1, true, nil
+	}
+
+	p, salt, key, err := decode(string(stored))
+	if err != nil {
+		return false, false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+
+	return true, p != DefaultParams, nil
+}
+
+var dummyHash = sync.OnceValues(func() ([]byte, error) {
+	return HashPassword("")
+})
+
+// VerifyDummy does the same argon2id work as VerifyPassword for logins which do not exist,
+// so response time does not tell whether login is registered.
+func VerifyDummy(password string) {
+	stored, err := dummyHash()
+	if err != nil {
+		return
+	}
+	_, _, _ = VerifyPassword(password, stored)
+}
+
+func decode(encoded string) (Params, []byte, []byte, error) {
+	var p Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrUnknownHashFormat, err)
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("%w: argon2 version %d", ErrUnknownHashFormat, version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrUnknownHashFormat, err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrUnknownHashFormat, err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrUnknownHashFormat, err)
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,46 @@
+package hash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashPassword(t *testing.T) {
+	h, err := HashPassword("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(h), "$argon2id$v=19$m=65536,t=1,p=4$"))
+
+	other, err := HashPassword("secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, h, other, "salt must differ")
+
+	ok, needsRehash, err := VerifyPassword("secret", h)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	ok, _, err = VerifyPassword("wrong", h)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyPasswordOutdatedParams(t *testing.T) {
+	weak := DefaultParams
+	weak.Memory = 8 * 1024
+
+	h, err := hashPassword("secret", weak)
+	require.NoError(t, err)
+
+	ok, needsRehash, err := VerifyPassword("secret", h)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash)
+}
+
+func TestVerifyPasswordUnknownFormat(t *testing.T) {
+	_, _, err := VerifyPassword("secret", []byte("$bcrypt$"))
+	assert.ErrorIs(t, err, ErrUnknownHashFormat)
+}
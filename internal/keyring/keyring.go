@@ -0,0 +1,229 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/morzisorn/gofermart/config"
+)
+
+const defaultKeyID = "default"
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Keyring signs tokens with the first configured key and verifies with any of them,
+// so tokens issued before key rotation stay valid until they expire.
+type Keyring struct {
+	method  jwt.SigningMethod
+	current string
+	keys    map[string]key
+	order   []string
+}
+
+type key struct {
+	sign   any
+	verify any
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var instance *Keyring
+
+// Init builds keyring of configured keys, secret key is used when none are set.
+// It is called at startup, so misconfigured keys stop the service before it serves requests.
+func Init(cnfg *config.Config) error {
+	keys := cnfg.JWTKeys
+	if len(keys) == 0 {
+		keys = []string{defaultKeyID + ":" + cnfg.SecretKey}
+	}
+
+	k, err := New(cnfg.JWTAlgorithm, keys)
+	if err != nil {
+		return fmt.Errorf("init keyring error: %w", err)
+	}
+
+	instance = k
+	return nil
+}
+
+// GetKeyring returns keyring built by Init
+func GetKeyring() *Keyring {
+	return instance
+}
+
+// New builds keyring from "kid:secret" entries for HS256
+// or "kid:/path/to/private.pem" entries for RS256 and EdDSA.
+func New(alg string, entries []string) (*Keyring, error) {
+	method := jwt.GetSigningMethod(alg)
+	switch method {
+	case jwt.SigningMethodHS256, jwt.SigningMethodRS256, jwt.SigningMethodEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", alg)
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("no JWT keys configured")
+	}
+
+	k := &Keyring{
+		method: method,
+		keys:   make(map[string]key, len(entries)),
+	}
+
+	for _, entry := range entries {
+		kid, value, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" || value == "" {
+			return nil, fmt.Errorf("JWT key must be formatted as kid:value")
+		}
+		if _, exists := k.keys[kid]; exists {
+			return nil, fmt.Errorf("duplicate JWT key id: %s", kid)
+		}
+
+		loaded, err := loadKey(method, value)
+		if err != nil {
+			return nil, fmt.Errorf("load JWT key %s error: %w", kid, err)
+		}
+
+		k.keys[kid] = loaded
+		k.order = append(k.order, kid)
+	}
+	k.current = k.order[0]
+
+	return k, nil
+}
+
+func loadKey(method jwt.SigningMethod, value string) (key, error) {
+	if method == jwt.SigningMethodHS256 {
+		return key{sign: []byte(value), verify: []byte(value)}, nil
+	}
+
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return key{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return key{}, errors.New("no PEM data found")
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return key{}, err
+		}
+	}
+
+	switch p := private.(type) {
+	case *rsa.PrivateKey:
+		if method != jwt.SigningMethodRS256 {
+			return key{}, fmt.Errorf("RSA key can not be used with %s", method.Alg())
+		}
+		return key{sign: p, verify: p.Public()}, nil
+	case ed25519.PrivateKey:
+		if method != jwt.SigningMethodEdDSA {
+			return key{}, fmt.Errorf("Ed25519 key can not be used with %s", method.Alg())
+		}
+		return key{sign: p, verify: p.Public()}, nil
+	default:
+		return key{}, fmt.Errorf("unsupported private key type %T", private)
+	}
+}
+
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.current
+
+	return token.SignedString(k.keys[k.current].sign)
+}
+
+// Parse validates token signed by any key of the keyring.
+// Tokens without kid were issued before rotation support and are checked with the default key.
+func (k *Keyring) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = defaultKeyID
+		}
+
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+		}
+		return key.verify, nil
+	}, jwt.WithValidMethods([]string{k.method.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// JWKS returns public keys for other services. It is empty for HS256 keys, they are secret.
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	for _, kid := range k.order {
+		if jwk, ok := toJWK(kid, k.method.Alg(), k.keys[kid].verify); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	return set
+}
+
+func toJWK(kid, alg string, public crypto.PublicKey) (JWK, bool) {
+	enc := base64.RawURLEncoding
+
+	switch p := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: alg,
+			N:   enc.EncodeToString(p.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(p.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: kid,
+			Use: "sig",
+			Alg: alg,
+			Crv: "Ed25519",
+			X:   enc.EncodeToString(p),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
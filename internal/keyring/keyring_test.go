@@ -0,0 +1,84 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"login": "user",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old, err := New("HS256", []string{"v1:old-secret"})
+	require.NoError(t, err)
+
+	token, err := old.Sign(claims())
+	require.NoError(t, err)
+
+	rotated, err := New("HS256", []string{"v2:new-secret", "v1:old-secret"})
+	require.NoError(t, err)
+
+	parsed, err := rotated.Parse(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user", parsed["login"])
+
+	retired, err := New("HS256", []string{"v2:new-secret"})
+	require.NoError(t, err)
+
+	_, err = retired.Parse(token)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestTokenWithoutKid(t *testing.T) {
+	k, err := New("HS256", []string{"default:secret"})
+	require.NoError(t, err)
+
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = k.Parse(legacy)
+	assert.NoError(t, err)
+}
+
+func TestEdDSA(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	k, err := New("EdDSA", []string{"ed1:" + path})
+	require.NoError(t, err)
+
+	token, err := k.Sign(claims())
+	require.NoError(t, err)
+
+	_, err = k.Parse(token)
+	require.NoError(t, err)
+
+	jwks := k.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "ed1", jwks.Keys[0].Kid)
+
+	hmac, err := New("HS256", []string{"v1:secret"})
+	require.NoError(t, err)
+	assert.Empty(t, hmac.JWKS().Keys)
+}
@@ -1,12 +1,16 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
 	Login     string   `json:"login"`
-	Password  [32]byte `json:"-"`
-	Current   float64  `json:"current"`
-	Withdrawn float64  `json:"withdrawn"`
+	Password  []byte   `json:"-"`
+	Current   Money    `json:"current"`
+	Withdrawn Money    `json:"withdrawn"`
+	Role      string   `json:"role"`
 }
 
 type ParseUserRegister struct {
@@ -14,30 +18,139 @@ type ParseUserRegister struct {
 	Password string `json:"password"`
 }
 
+type SetRole struct {
+	Role string `json:"role"`
+}
+
+type ChangePassword struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
 type Order struct {
 	Number     string    `json:"number"`
 	UploadedAt time.Time `json:"uploaded_at"`
 	UserLogin  string    `json:"user_login,omitempty"`
 	Status     string    `json:"status"`
-	Accrual    float64   `json:"accrual,omitempty"`
+	Accrual    Money     `json:"accrual,omitempty"`
+}
+
+// OrderJob is an order claimed from processing queue
+type OrderJob struct {
+	Order    Order
+	Attempts int           //Claims of this order so far, including current one
+	Age      time.Duration //Time since upload by database clock, uploaded_at has no time zone
+}
+
+// OrderProcessing is order with state of its processing job, shown to operators
+type OrderProcessing struct {
+	Order
+	Attempts      int        `json:"attempts,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	ParkedAt      *time.Time `json:"parked_at,omitempty"`
+	ParkedReason  string     `json:"parked_reason,omitempty"`
 }
 
 type UserBalance struct {
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Current   Money `json:"current"`
+	Withdrawn Money `json:"withdrawn"`
 }
 
 type Withdrawal struct {
 	Number      string    `json:"order"`
 	ProcessedAt time.Time `json:"processed_at"`
 	UserLogin   string    `json:"user_login,omitempty"`
-	Sum         float64   `json:"sum"`
+	Sum         Money     `json:"sum"`
+}
+
+// BalanceAdjustment is a manual correction of user balance made by operator
+type BalanceAdjustment struct {
+	ID            int64     `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UserLogin     string    `json:"user_login,omitempty"`
+	Amount        Money     `json:"amount"`
+	Reason        string    `json:"reason"`
+	Note          string    `json:"note"`
+	OperatorLogin string    `json:"operator_login,omitempty"`
 }
 
 type LoyaltyOrder struct {
-	Order   string  `json:"order"`
-	Status  string  `json:"status"`
-	Accrual float64 `json:"accrual"`
+	Order   string `json:"order"`
+	Status  string `json:"status"`
+	Accrual Money  `json:"accrual"`
+}
+
+type LedgerEntry struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserLogin string    `json:"user_login,omitempty"`
+	Operation string    `json:"operation"`
+	Reference string    `json:"reference"`
+	Account   string    `json:"account"`
+	Amount    Money     `json:"amount"`
+}
+
+// OutboxEvent is a change other services are notified about, written in the same transaction as the change
+type OutboxEvent struct {
+	ID        int64           `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Type      string          `json:"type"`
+	Key       string          `json:"key"` //Number of order the event is about
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"-"`
+}
+
+type OrderProcessedEvent struct {
+	Number    string `json:"number"`
+	UserLogin string `json:"user_login"`
+	Accrual   Money  `json:"accrual"`
+}
+
+type WithdrawalEvent struct {
+	Order     string `json:"order"`
+	UserLogin string `json:"user_login"`
+	Sum       Money  `json:"sum"`
+}
+
+// ProcessingStatus is state of background order processing reported by health check
+type ProcessingStatus struct {
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"`
+}
+
+type Health struct {
+	Status     string           `json:"status"`
+	Processing ProcessingStatus `json:"processing"`
+}
+
+type Session struct {
+	ID          string
+	UserLogin   string
+	RefreshHash []byte
+	Revoked     bool
+	Expired     bool
+}
+
+type Tokens struct {
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in"` //Access token lifetime in seconds
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// IdempotentResponse is a response stored for Idempotency-Key replay
+type IdempotentResponse struct {
+	RequestHash []byte
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
 }
 
 const (
@@ -52,4 +165,54 @@ const (
 	LoyaltyStatusINVALID    string = "INVALID"
 	LoyaltyStatusPROCESSING string = "PROCESSING"
 	LoyaltyStatusPROCESSED  string = "PROCESSED"
+
+	// Accrual responded 204: order is not registered in accrual system yet
+	LoyaltyStatusNOTREGISTERED string = "NOT_REGISTERED"
+)
+
+const (
+	RoleUser    string = "user"
+	RoleSupport string = "support"
+	RoleAdmin   string = "admin"
+)
+
+const (
+	LedgerOperationOPENING    string = "OPENING"
+	LedgerOperationACCRUAL    string = "ACCRUAL"
+	LedgerOperationWITHDRAWAL string = "WITHDRAWAL"
+	LedgerOperationFORFEIT    string = "FORFEIT"
+	LedgerOperationADJUSTMENT string = "ADJUSTMENT"
+)
+
+// Every ledger operation moves amount between two accounts, so entries of an operation sum to zero
+const (
+	LedgerAccountOpening    string = "opening"
+	LedgerAccountAccrual    string = "accrual"
+	LedgerAccountCurrent    string = "current"
+	LedgerAccountWithdrawn  string = "withdrawn"
+	LedgerAccountForfeited  string = "forfeited"
+	LedgerAccountAdjustment string = "adjustment"
+)
+
+const (
+	AdjustmentReasonGOODWILL             string = "GOODWILL"
+	AdjustmentReasonACCRUALCORRECTION    string = "ACCRUAL_CORRECTION"
+	AdjustmentReasonWITHDRAWALCORRECTION string = "WITHDRAWAL_CORRECTION"
+	AdjustmentReasonOTHER                string = "OTHER"
+)
+
+const (
+	OutboxEventOrderProcessed string = "order.processed"
+	OutboxEventWithdrawal     string = "balance.withdrawn"
+)
+
+const (
+	BreakerClosed   string = "closed"
+	BreakerOpen     string = "open"
+	BreakerHalfOpen string = "half-open"
+)
+
+const (
+	HealthOK       string = "ok"
+	HealthDegraded string = "degraded"
 )
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount of loyalty points stored in hundredths (kopecks) to avoid float rounding drift.
+// In JSON it is represented as a plain number: 500, 500.5, 729.98.
+type Money int64
+
+const moneyScale = 100
+
+func NewMoney(units, cents int64) Money {
+	return Money(units*moneyScale + cents)
+}
+
+func ParseMoney(s string) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("invalid money value: %q", s)
+	}
+
+	r.Mul(r, big.NewRat(moneyScale, 1))
+
+	// Round half away from zero to whole kopecks
+	num, den := new(big.Int).Set(r.Num()), r.Denom()
+	half := new(big.Int).Quo(den, big.NewInt(2))
+	if num.Sign() < 0 {
+		num.Sub(num, half)
+	} else {
+		num.Add(num, half)
+	}
+	num.Quo(num, den)
+
+	if !num.IsInt64() {
+		return 0, fmt.Errorf("money value out of range: %q", s)
+	}
+
+	return Money(num.Int64()), nil
+}
+
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	units, cents := v/moneyScale, v%moneyScale
+	switch {
+	case cents == 0:
+		return sign + strconv.FormatInt(units, 10)
+	case cents%10 == 0:
+		return fmt.Sprintf("%s%d.%d", sign, units, cents/10)
+	default:
+		return fmt.Sprintf("%s%d.%02d", sign, units, cents)
+	}
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	v, err := ParseMoney(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+
+	*m = v
+	return nil
+}
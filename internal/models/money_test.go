@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"500", NewMoney(500, 0)},
+		{"500.5", NewMoney(500, 50)},
+		{"729.98", NewMoney(729, 98)},
+		{"0.1", NewMoney(0, 10)},
+		{"1.005", NewMoney(1, 1)},
+		{"-2.5", -NewMoney(2, 50)},
+		{"1e2", NewMoney(100, 0)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	_, err := ParseMoney("abc")
+	assert.Error(t, err)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	b, err := json.Marshal(UserBalance{Current: NewMoney(500, 50), Withdrawn: NewMoney(42, 0)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"current":500.5,"withdrawn":42}`, string(b))
+
+	var w Withdrawal
+	require.NoError(t, json.Unmarshal([]byte(`{"order":"2377225624","sum":751.99}`), &w))
+	assert.Equal(t, NewMoney(751, 99), w.Sum)
+}
@@ -0,0 +1,48 @@
+package models
+
+// orderTransitions is the order status state machine: NEW → PROCESSING → PROCESSED or INVALID.
+// Accrual may report final status at the first check, so NEW order can be finished directly.
+var orderTransitions = map[string][]string{
+	OrderStatusNEW:        {OrderStatusPROCESSING, OrderStatusPROCESSED, OrderStatusINVALID},
+	OrderStatusPROCESSING: {OrderStatusPROCESSED, OrderStatusINVALID},
+}
+
+// CanTransitionOrder reports whether order may move from one status to another
+func CanTransitionOrder(from, to string) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStatusesBefore returns statuses from which order may move to status
+func OrderStatusesBefore(status string) []string {
+	var from []string
+	for s := range orderTransitions {
+		if CanTransitionOrder(s, status) {
+			from = append(from, s)
+		}
+	}
+	return from
+}
+
+// IsFinalOrderStatus reports whether order status can not change anymore
+func IsFinalOrderStatus(status string) bool {
+	return status == OrderStatusPROCESSED || status == OrderStatusINVALID
+}
+
+// OrderStatusFromLoyalty maps accrual status to order status, false for NOT_REGISTERED and unknown ones
+func OrderStatusFromLoyalty(loyaltyStatus string) (string, bool) {
+	switch loyaltyStatus {
+	case LoyaltyStatusREGISTERED, LoyaltyStatusPROCESSING:
+		return OrderStatusPROCESSING, true
+	case LoyaltyStatusPROCESSED:
+		return OrderStatusPROCESSED, true
+	case LoyaltyStatusINVALID:
+		return OrderStatusINVALID, true
+	default:
+		return "", false
+	}
+}
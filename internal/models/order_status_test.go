@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionOrder(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{OrderStatusNEW, OrderStatusPROCESSING, true},
+		{OrderStatusNEW, OrderStatusPROCESSED, true},
+		{OrderStatusNEW, OrderStatusINVALID, true},
+		{OrderStatusPROCESSING, OrderStatusPROCESSED, true},
+		{OrderStatusPROCESSING, OrderStatusINVALID, true},
+
+		{OrderStatusNEW, OrderStatusNEW, false},
+		{OrderStatusPROCESSING, OrderStatusNEW, false},
+		{OrderStatusPROCESSING, OrderStatusPROCESSING, false},
+		{OrderStatusPROCESSED, OrderStatusPROCESSING, false},
+		{OrderStatusPROCESSED, OrderStatusPROCESSED, false},
+		{OrderStatusPROCESSED, OrderStatusINVALID, false},
+		{OrderStatusINVALID, OrderStatusPROCESSED, false},
+		{OrderStatusINVALID, OrderStatusNEW, false},
+		{"UNKNOWN", OrderStatusPROCESSING, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CanTransitionOrder(tt.from, tt.to), "%s → %s", tt.from, tt.to)
+	}
+}
+
+func TestOrderStatusesBefore(t *testing.T) {
+	tests := []struct {
+		status string
+		want   []string
+	}{
+		{OrderStatusNEW, nil},
+		{OrderStatusPROCESSING, []string{OrderStatusNEW}},
+		{OrderStatusPROCESSED, []string{OrderStatusNEW, OrderStatusPROCESSING}},
+		{OrderStatusINVALID, []string{OrderStatusNEW, OrderStatusPROCESSING}},
+	}
+
+	for _, tt := range tests {
+		assert.ElementsMatch(t, tt.want, OrderStatusesBefore(tt.status), tt.status)
+	}
+}
+
+func TestOrderStatusFromLoyalty(t *testing.T) {
+	tests := []struct {
+		loyalty string
+		want    string
+		ok      bool
+	}{
+		{LoyaltyStatusREGISTERED, OrderStatusPROCESSING, true},
+		{LoyaltyStatusPROCESSING, OrderStatusPROCESSING, true},
+		{LoyaltyStatusPROCESSED, OrderStatusPROCESSED, true},
+		{LoyaltyStatusINVALID, OrderStatusINVALID, true},
+		{LoyaltyStatusNOTREGISTERED, "", false},
+		{"UNKNOWN", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := OrderStatusFromLoyalty(tt.loyalty)
+		assert.Equal(t, tt.ok, ok, tt.loyalty)
+		assert.Equal(t, tt.want, got, tt.loyalty)
+	}
+}
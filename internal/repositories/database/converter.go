@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
@@ -9,12 +10,47 @@ import (
 	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
 )
 
-func pgxFloat4ToFloat64(i pgtype.Float4) (float64, error) {
-	if i.Valid {
-		return float64(i.Float32), nil
+const moneyExp = -2 //Money is stored in hundredths
+
+func pgNumericToMoney(n pgtype.Numeric) (models.Money, error) {
+	if !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite {
+		return 0, fmt.Errorf("invalid numeric")
+	}
+
+	v := new(big.Int).Set(n.Int)
+	shift := n.Exp - moneyExp
+
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil)
+	if shift >= 0 {
+		v.Mul(v, pow)
+	} else {
+		rem := new(big.Int)
+		v.QuoRem(v, pow, rem)
+		if rem.Sign() != 0 {
+			return 0, fmt.Errorf("numeric has more than 2 fractional digits")
+		}
+	}
+
+	if !v.IsInt64() {
+		return 0, fmt.Errorf("numeric out of range")
+	}
+
+	return models.Money(v.Int64()), nil
+}
+
+func moneyToPgNumeric(m models.Money) pgtype.Numeric {
+	return pgtype.Numeric{
+		Int:   big.NewInt(int64(m)),
+		Exp:   moneyExp,
+		Valid: true,
 	}
+}
 
-	return 0, fmt.Errorf("invalid float")
+func abs(i int32) int32 {
+	if i < 0 {
+		return -i
+	}
+	return i
 }
 
 func pgTimeToTime(pgTime pgtype.Timestamp) (time.Time, error) {
@@ -25,6 +61,14 @@ func pgTimeToTime(pgTime pgtype.Timestamp) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("invalid time")
 }
 
+// pgTimeToPtr maps NULL to nil, e.g. columns of LEFT JOIN
+func pgTimeToPtr(pgTime pgtype.Timestamp) *time.Time {
+	if !pgTime.Valid {
+		return nil
+	}
+	return &pgTime.Time
+}
+
 func pgxTextToString(s pgtype.Text) (string, error) {
 	if s.Valid {
 		return s.String, nil
@@ -58,7 +102,7 @@ func dbToModelOrder(o *gen.Order) (*models.Order, error) {
 		return nil, fmt.Errorf("convert db to model order error: %w", err)
 	}
 
-	accrual, _ := pgxFloat4ToFloat64(o.Accrual)
+	accrual, _ := pgNumericToMoney(o.Accrual)
 
 	return &models.Order{
 		Number:     o.Number,
@@ -69,6 +113,79 @@ func dbToModelOrder(o *gen.Order) (*models.Order, error) {
 	}, nil
 }
 
+func dbToModelOrderJobs(rows *[]gen.ClaimOrderJobsRow) (*[]models.OrderJob, error) {
+	jobs := make([]models.OrderJob, len(*rows))
+
+	for i, r := range *rows {
+		order, err := dbToModelOrder(&gen.Order{
+			Number:     r.Number,
+			UploadedAt: r.UploadedAt,
+			UserLogin:  r.UserLogin,
+			Status:     r.Status,
+			Accrual:    r.Accrual,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		jobs[i] = models.OrderJob{
+			Order:    *order,
+			Attempts: int(r.Attempts),
+			Age:      time.Duration(r.AgeSeconds) * time.Second,
+		}
+	}
+	return &jobs, nil
+}
+
+func dbToModelOrdersProcessing(rows *[]gen.GetUserOrdersProcessingRow) (*[]models.OrderProcessing, error) {
+	orders := make([]models.OrderProcessing, len(*rows))
+
+	for i, r := range *rows {
+		order, err := dbToModelOrder(&gen.Order{
+			Number:     r.Number,
+			UploadedAt: r.UploadedAt,
+			UserLogin:  r.UserLogin,
+			Status:     r.Status,
+			Accrual:    r.Accrual,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		orders[i] = models.OrderProcessing{
+			Order:         *order,
+			Attempts:      int(r.Attempts.Int32),
+			NextAttemptAt: pgTimeToPtr(r.NextAttemptAt),
+			LastCheckedAt: pgTimeToPtr(r.LastCheckedAt),
+			LockedUntil:   pgTimeToPtr(r.LockedUntil),
+			ParkedAt:      pgTimeToPtr(r.ParkedAt),
+			ParkedReason:  r.ParkedReason.String,
+		}
+	}
+	return &orders, nil
+}
+
+func dbToModelOutboxEvents(rows *[]gen.ClaimOutboxEventsRow) (*[]models.OutboxEvent, error) {
+	events := make([]models.OutboxEvent, len(*rows))
+
+	for i, r := range *rows {
+		createdAt, err := pgTimeToTime(r.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("convert db to model outbox event error: %w", err)
+		}
+
+		events[i] = models.OutboxEvent{
+			ID:        r.ID,
+			CreatedAt: createdAt,
+			Type:      r.EventType,
+			Key:       r.AggregateID,
+			Payload:   r.Payload,
+			Attempts:  int(r.Attempts),
+		}
+	}
+	return &events, nil
+}
+
 func dbToModelWithdrawals(dbOrders *[]gen.Withdrawal) (*[]models.Withdrawal, error) {
 	withdrawals := make([]models.Withdrawal, len(*dbOrders))
 	for i, o := range *dbOrders {
@@ -77,7 +194,7 @@ func dbToModelWithdrawals(dbOrders *[]gen.Withdrawal) (*[]models.Withdrawal, err
 			return nil, fmt.Errorf("convert db to model withdrawal error: %w", err)
 		}
 
-		sum, err := pgxFloat4ToFloat64(o.Sum)
+		sum, err := pgNumericToMoney(o.Sum)
 		if err != nil {
 			return nil, fmt.Errorf("convert db to model withdrawal error: %w", err)
 		}
@@ -90,3 +207,51 @@ func dbToModelWithdrawals(dbOrders *[]gen.Withdrawal) (*[]models.Withdrawal, err
 	}
 	return &withdrawals, nil
 }
+
+func dbToModelLedgerEntries(dbEntries *[]gen.LedgerEntry) (*[]models.LedgerEntry, error) {
+	entries := make([]models.LedgerEntry, len(*dbEntries))
+	for i, e := range *dbEntries {
+		createdAt, err := pgTimeToTime(e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("convert db to model ledger entry error: %w", err)
+		}
+
+		amount, err := pgNumericToMoney(e.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("convert db to model ledger entry error: %w", err)
+		}
+
+		entries[i] = models.LedgerEntry{
+			ID:        e.ID,
+			CreatedAt: createdAt,
+			UserLogin: e.UserLogin,
+			Operation: e.Operation,
+			Reference: e.Reference,
+			Account:   e.Account,
+			Amount:    amount,
+		}
+	}
+	return &entries, nil
+}
+
+func dbToModelAdjustment(a *gen.BalanceAdjustment) (*models.BalanceAdjustment, error) {
+	createdAt, err := pgTimeToTime(a.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("convert db to model adjustment error: %w", err)
+	}
+
+	amount, err := pgNumericToMoney(a.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("convert db to model adjustment error: %w", err)
+	}
+
+	return &models.BalanceAdjustment{
+		ID:            a.ID,
+		CreatedAt:     createdAt,
+		UserLogin:     a.UserLogin,
+		Amount:        amount,
+		Reason:        a.Reason,
+		Note:          a.Note,
+		OperatorLogin: a.OperatorLogin,
+	}, nil
+}
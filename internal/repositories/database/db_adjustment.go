@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+)
+
+type AdjustmentRepository interface {
+	AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.BalanceAdjustment, error)
+	GetUserAdjustments(ctx context.Context, login string) (*[]models.BalanceAdjustment, error)
+}
+
+type adjustmentRepository struct {
+	q  *gen.Queries
+	db *pgxpool.Pool
+}
+
+func NewAdjustmentRepository(q *gen.Queries, db *pgxpool.Pool) AdjustmentRepository {
+	return &adjustmentRepository{
+		q:  q,
+		db: db,
+	}
+}
+
+// AdjustBalance records adjustment, changes balance counter and writes ledger entries in one transaction.
+// Negative adjustment can not take balance below zero.
+func (r *adjustmentRepository) AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.BalanceAdjustment, error) {
+	var created *models.BalanceAdjustment
+
+	err := withTransaction(ctx, r.db, func(qtx *gen.Queries) error {
+		dbAdj, err := qtx.CreateBalanceAdjustment(ctx, gen.CreateBalanceAdjustmentParams{
+			UserLogin:     adj.UserLogin,
+			Amount:        moneyToPgNumeric(adj.Amount),
+			Reason:        adj.Reason,
+			Note:          adj.Note,
+			OperatorLogin: adj.OperatorLogin,
+		})
+		if err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+				return errs.ErrUserNotFound
+			}
+			return fmt.Errorf("create balance adjustment error: %w", err)
+		}
+
+		if err := qtx.UpdateUserBalance(ctx, gen.UpdateUserBalanceParams{
+			Login:     adj.UserLogin,
+			Current:   moneyToPgNumeric(adj.Amount),
+			Withdrawn: moneyToPgNumeric(0),
+		}); err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23514" {
+				return errs.ErrInsufficientBalance
+			}
+			return fmt.Errorf("update user balance error: %w", err)
+		}
+
+		if err := transfer(ctx, qtx, adj.UserLogin, models.LedgerOperationADJUSTMENT, fmt.Sprint(dbAdj.ID),
+			models.LedgerAccountAdjustment, models.LedgerAccountCurrent, adj.Amount); err != nil {
+			return err
+		}
+
+		created, err = dbToModelAdjustment(&dbAdj)
+		return err
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("adjust balance db error: %w", err)
+	}
+	return created, nil
+}
+
+func (r *adjustmentRepository) GetUserAdjustments(ctx context.Context, login string) (*[]models.BalanceAdjustment, error) {
+	dbAdjs, err := r.q.GetUserAdjustments(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("get user adjustments db error: %w", err)
+	}
+
+	adjs := make([]models.BalanceAdjustment, len(dbAdjs))
+	for i := range dbAdjs {
+		adj, err := dbToModelAdjustment(&dbAdjs[i])
+		if err != nil {
+			return nil, fmt.Errorf("get user adjustments db error: %w", err)
+		}
+		adjs[i] = *adj
+	}
+	return &adjs, nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustBalance(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	q := gen.New(db)
+	users := NewUserRepository(q, db)
+	adjustments := NewAdjustmentRepository(q, db)
+
+	suffix := time.Now().UnixNano()
+	login := fmt.Sprintf("adjust_%d", suffix)
+	operator := fmt.Sprintf("operator_%d", suffix)
+	require.NoError(t, users.RegisterUser(ctx, models.User{Login: login}))
+	require.NoError(t, users.RegisterUser(ctx, models.User{Login: operator}))
+
+	adj, err := adjustments.AdjustBalance(ctx, &models.BalanceAdjustment{
+		UserLogin:     login,
+		Amount:        models.NewMoney(25, 50),
+		Reason:        models.AdjustmentReasonGOODWILL,
+		Note:          "delayed delivery",
+		OperatorLogin: operator,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, operator, adj.OperatorLogin)
+
+	_, err = adjustments.AdjustBalance(ctx, &models.BalanceAdjustment{
+		UserLogin:     login,
+		Amount:        models.NewMoney(-30, 0),
+		Reason:        models.AdjustmentReasonACCRUALCORRECTION,
+		Note:          "accrual was counted twice",
+		OperatorLogin: operator,
+	})
+	assert.True(t, errors.Is(err, errs.ErrInsufficientBalance), err)
+
+	user, err := users.GetUser(ctx, login)
+	require.NoError(t, err)
+	assert.Equal(t, models.NewMoney(25, 50), user.Current)
+
+	ledger, err := NewLedgerRepository(q).GetLedgerBalance(ctx, login)
+	require.NoError(t, err)
+	assert.Equal(t, user.Current, ledger.Current)
+
+	history, err := adjustments.GetUserAdjustments(ctx, login)
+	require.NoError(t, err)
+	assert.Len(t, *history, 1)
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+)
+
+type IdempotencyRepository interface {
+	ClaimIdempotencyKey(ctx context.Context, login, key string, requestHash []byte, windowSeconds, leaseSeconds int) (bool, error)
+	GetIdempotencyKey(ctx context.Context, login, key string) (*models.IdempotentResponse, error)
+	CompleteIdempotencyKey(ctx context.Context, login, key string, resp *models.IdempotentResponse) error
+	ReleaseIdempotencyKey(ctx context.Context, login, key string) error
+}
+
+type idempotencyRepository struct {
+	q *gen.Queries
+}
+
+func NewIdempotencyRepository(q *gen.Queries) IdempotencyRepository {
+	return &idempotencyRepository{q: q}
+}
+
+// ClaimIdempotencyKey drops keys older than window and reports whether key was free and is now taken.
+// Unfinished key of the same request whose lease has expired is taken over, its owner has crashed.
+func (r *idempotencyRepository) ClaimIdempotencyKey(ctx context.Context, login, key string, requestHash []byte, windowSeconds, leaseSeconds int) (bool, error) {
+	err := r.q.DeleteExpiredIdempotencyKeys(ctx, gen.DeleteExpiredIdempotencyKeysParams{
+		UserLogin:     login,
+		WindowSeconds: int32(windowSeconds),
+	})
+	if err != nil {
+		return false, fmt.Errorf("delete expired idempotency keys db error: %w", err)
+	}
+
+	claimed, err := r.q.ClaimIdempotencyKey(ctx, gen.ClaimIdempotencyKeyParams{
+		UserLogin:    login,
+		Key:          key,
+		RequestHash:  requestHash,
+		LeaseSeconds: int32(leaseSeconds),
+	})
+	if err != nil {
+		return false, fmt.Errorf("claim idempotency key db error: %w", err)
+	}
+
+	return claimed == 1, nil
+}
+
+func (r *idempotencyRepository) GetIdempotencyKey(ctx context.Context, login, key string) (*models.IdempotentResponse, error) {
+	k, err := r.q.GetIdempotencyKey(ctx, gen.GetIdempotencyKeyParams{
+		UserLogin: login,
+		Key:       key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get idempotency key db error: %w", err)
+	}
+
+	return &models.IdempotentResponse{
+		RequestHash: k.RequestHash,
+		Completed:   k.StatusCode.Valid,
+		StatusCode:  int(k.StatusCode.Int32),
+		ContentType: k.ContentType.String,
+		Body:        k.ResponseBody,
+	}, nil
+}
+
+func (r *idempotencyRepository) CompleteIdempotencyKey(ctx context.Context, login, key string, resp *models.IdempotentResponse) error {
+	err := r.q.CompleteIdempotencyKey(ctx, gen.CompleteIdempotencyKeyParams{
+		UserLogin:    login,
+		Key:          key,
+		StatusCode:   pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+		ContentType:  pgtype.Text{String: resp.ContentType, Valid: true},
+		ResponseBody: resp.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("complete idempotency key db error: %w", err)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) ReleaseIdempotencyKey(ctx context.Context, login, key string) error {
+	err := r.q.ReleaseIdempotencyKey(ctx, gen.ReleaseIdempotencyKeyParams{
+		UserLogin: login,
+		Key:       key,
+	})
+	if err != nil {
+		return fmt.Errorf("release idempotency key db error: %w", err)
+	}
+	return nil
+}
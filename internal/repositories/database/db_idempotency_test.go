@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeyLease(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	q := gen.New(db)
+	users := NewUserRepository(q, db)
+	keys := NewIdempotencyRepository(q)
+
+	login := fmt.Sprintf("idempotency_%d", time.Now().UnixNano())
+	require.NoError(t, users.RegisterUser(ctx, models.User{Login: login}))
+
+	claimed, err := keys.ClaimIdempotencyKey(ctx, login, "key", []byte("hash"), 3600, 0)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = keys.ClaimIdempotencyKey(ctx, login, "key", []byte("other"), 3600, 60)
+	require.NoError(t, err)
+	assert.False(t, claimed, "expired key is not taken over by a different request")
+
+	claimed, err = keys.ClaimIdempotencyKey(ctx, login, "key", []byte("hash"), 3600, 60)
+	require.NoError(t, err)
+	assert.True(t, claimed, "expired key is taken over by the same request")
+
+	claimed, err = keys.ClaimIdempotencyKey(ctx, login, "key", []byte("hash"), 3600, 60)
+	require.NoError(t, err)
+	assert.False(t, claimed, "leased key stays in progress")
+
+	require.NoError(t, keys.CompleteIdempotencyKey(ctx, login, "key", &models.IdempotentResponse{StatusCode: 200}))
+	resp, err := keys.GetIdempotencyKey(ctx, login, "key")
+	require.NoError(t, err)
+	assert.True(t, resp.Completed)
+}
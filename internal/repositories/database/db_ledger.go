@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+)
+
+type LedgerRepository interface {
+	GetLedgerBalance(ctx context.Context, login string) (*models.UserBalance, error)
+	GetUserLedger(ctx context.Context, login string) (*[]models.LedgerEntry, error)
+}
+
+type ledgerRepository struct {
+	q *gen.Queries
+}
+
+func NewLedgerRepository(q *gen.Queries) LedgerRepository {
+	return &ledgerRepository{q: q}
+}
+
+func (r *ledgerRepository) GetLedgerBalance(ctx context.Context, login string) (*models.UserBalance, error) {
+	b, err := r.q.GetLedgerBalance(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("get ledger balance db error: %w", err)
+	}
+
+	current, err := pgNumericToMoney(b.Current)
+	if err != nil {
+		return nil, fmt.Errorf("get ledger balance db error: %w", err)
+	}
+
+	withdrawn, err := pgNumericToMoney(b.Withdrawn)
+	if err != nil {
+		return nil, fmt.Errorf("get ledger balance db error: %w", err)
+	}
+
+	return &models.UserBalance{
+		Current:   current,
+		Withdrawn: withdrawn,
+	}, nil
+}
+
+func (r *ledgerRepository) GetUserLedger(ctx context.Context, login string) (*[]models.LedgerEntry, error) {
+	dbEntries, err := r.q.GetUserLedger(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("get user ledger db error: %w", err)
+	}
+
+	return dbToModelLedgerEntries(&dbEntries)
+}
+
+// transfer writes balanced pair of ledger entries moving amount from one user account to another.
+// Must be called inside the transaction that changes balance counters.
+func transfer(ctx context.Context, qtx *gen.Queries, login, operation, reference, from, to string, amount models.Money) error {
+	entries := []struct {
+		account string
+		amount  models.Money
+	}{
+		{from, -amount},
+		{to, amount},
+	}
+
+	for _, e := range entries {
+		if err := qtx.AddLedgerEntry(ctx, gen.AddLedgerEntryParams{
+			UserLogin: login,
+			Operation: operation,
+			Reference: reference,
+			Account:   e.account,
+			Amount:    moneyToPgNumeric(e.amount),
+		}); err != nil {
+			return fmt.Errorf("add ledger entry error: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+)
+
+type LoginAttemptRepository interface {
+	RecordLoginAttempt(ctx context.Context, key string, windowSeconds int, lockout func(attempts int) int) (int, error)
+	ReleaseLoginAttempt(ctx context.Context, key string, limit int) error
+	GetLoginLock(ctx context.Context, key string) (int, error)
+	ResetLoginAttempts(ctx context.Context, key string) error
+	DeleteStaleLoginAttempts(ctx context.Context, windowSeconds int) (int64, error)
+}
+
+type loginAttemptRepository struct {
+	q  *gen.Queries
+	db *pgxpool.Pool
+}
+
+func NewLoginAttemptRepository(q *gen.Queries, db *pgxpool.Pool) LoginAttemptRepository {
+	return &loginAttemptRepository{
+		q:  q,
+		db: db,
+	}
+}
+
+// RecordLoginAttempt counts attempt of unlocked key within window and locks the key
+// for lockout(attempts) seconds in the same transaction, so concurrent attempts wait for
+// the lock. Returns attempts, zero if key is locked and attempt is not counted.
+func (r *loginAttemptRepository) RecordLoginAttempt(ctx context.Context, key string, windowSeconds int, lockout func(attempts int) int) (int, error) {
+	var attempts int32
+	err := withTransaction(ctx, r.db, func(qtx *gen.Queries) error {
+		var err error
+		attempts, err = qtx.RecordLoginAttempt(ctx, gen.RecordLoginAttemptParams{
+			Key:           key,
+			WindowSeconds: int32(windowSeconds),
+		})
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		lock := lockout(int(attempts))
+		if lock == 0 {
+			return nil
+		}
+		return qtx.LockLoginKey(ctx, gen.LockLoginKeyParams{
+			LockSeconds: int32(lock),
+			Key:         key,
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("record login attempt db error: %w", err)
+	}
+
+	return int(attempts), nil
+}
+
+// ReleaseLoginAttempt uncounts attempt, the lock is lifted once attempts are below limit
+func (r *loginAttemptRepository) ReleaseLoginAttempt(ctx context.Context, key string, limit int) error {
+	err := r.q.ReleaseLoginAttempt(ctx, gen.ReleaseLoginAttemptParams{
+		MaxAttempts: int32(limit),
+		Key:         key,
+	})
+	if err != nil {
+		return fmt.Errorf("release login attempt db error: %w", err)
+	}
+	return nil
+}
+
+// GetLoginLock returns seconds left until key is unlocked, zero if it is not locked
+func (r *loginAttemptRepository) GetLoginLock(ctx context.Context, key string) (int, error) {
+	seconds, err := r.q.GetLoginLock(ctx, key)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("get login lock db error: %w", err)
+	}
+	return int(seconds), nil
+}
+
+func (r *loginAttemptRepository) ResetLoginAttempts(ctx context.Context, key string) error {
+	if err := r.q.ResetLoginAttempts(ctx, key); err != nil {
+		return fmt.Errorf("reset login attempts db error: %w", err)
+	}
+	return nil
+}
+
+// DeleteStaleLoginAttempts drops unlocked counters idle longer than window
+func (r *loginAttemptRepository) DeleteStaleLoginAttempts(ctx context.Context, windowSeconds int) (int64, error) {
+	deleted, err := r.q.DeleteStaleLoginAttempts(ctx, int32(windowSeconds))
+	if err != nil {
+		return 0, fmt.Errorf("delete stale login attempts db error: %w", err)
+	}
+	return deleted, nil
+}
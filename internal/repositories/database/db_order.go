@@ -16,32 +16,42 @@ import (
 
 type OrderRepository interface {
 	UploadOrder(ctx context.Context, login, number string) (string, error)
-	Withdraw(ctx context.Context, login, number string, sum float64) error
+	Withdraw(ctx context.Context, login, number string, sum models.Money) error
 	GetUserOrders(ctx context.Context, login string) (*[]models.Order, error)
 	GetUserWithdrawals(ctx context.Context, login string) (*[]models.Withdrawal, error)
 	UpdateOrderStatus(ctx context.Context, number, status string) error
 	GetOrdersWithStatus(ctx context.Context, status string) (*[]models.Order, error)
-	OrderProcessed(ctx context.Context, login, number string, accrual float64) error
-	GetUpprocessedOrders(ctx context.Context) (*[]models.Order, error)
+	OrderProcessed(ctx context.Context, login, number string, accrual models.Money) error
 	GetOrderByNumber(ctx context.Context, number string) (*models.Order, error)
+	ParkOrder(ctx context.Context, number, reason string) error
 }
 
 type orderRepository struct {
 	q  *gen.Queries
 	db *pgxpool.Pool
+
+	// Events are recorded only when relay publishes them, nothing would delete them otherwise
+	events bool
 }
 
-func NewOrderRepository(q *gen.Queries, db *pgxpool.Pool) OrderRepository {
+func NewOrderRepository(q *gen.Queries, db *pgxpool.Pool, events bool) OrderRepository {
 	return &orderRepository{
-		q:  q,
-		db: db,
+		q:      q,
+		db:     db,
+		events: events,
 	}
 }
 
+// UploadOrder saves order and enqueues its processing job in one transaction
 func (r *orderRepository) UploadOrder(ctx context.Context, login, number string) (string, error) {
-	err := r.q.UploadOrder(ctx, gen.UploadOrderParams{
-		UserLogin: login,
-		Number:    number,
+	err := withTransaction(ctx, r.db, func(qtx *gen.Queries) error {
+		if err := qtx.UploadOrder(ctx, gen.UploadOrderParams{
+			UserLogin: login,
+			Number:    number,
+		}); err != nil {
+			return err
+		}
+		return qtx.EnqueueOrderJob(ctx, number)
 	})
 	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
@@ -60,21 +70,28 @@ func (r *orderRepository) UploadOrder(ctx context.Context, login, number string)
 	return login, nil
 }
 
-func (r *orderRepository) Withdraw(ctx context.Context, login, number string, sum float64) error {
-	user, err := r.q.GetUser(ctx, login)
-	if err != nil {
-		return err
-	}
-
-	if float64(user.Current.Float32) < sum {
-		return fmt.Errorf("withdraw error: %w", errs.ErrInsufficientBalance)
-	}
+// Withdraw checks and debits balance with one conditional UPDATE, the row lock it takes
+// serializes concurrent withdrawals of the same user until the transaction ends.
+func (r *orderRepository) Withdraw(ctx context.Context, login, number string, sum models.Money) error {
+	err := withTransaction(ctx, r.db, func(qtx *gen.Queries) error {
+		debited, err := qtx.DebitUserBalance(ctx, gen.DebitUserBalanceParams{
+			Amount: moneyToPgNumeric(sum),
+			Login:  login,
+		})
+		if err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23514" {
+				return errs.ErrInsufficientBalance
+			}
+			return fmt.Errorf("debit user balance error: %w", err)
+		}
+		if debited == 0 {
+			return errs.ErrInsufficientBalance
+		}
 
-	err = withTransaction(ctx, r.db, func(qtx *gen.Queries) error {
 		if err := qtx.UploadWithdrawal(ctx, gen.UploadWithdrawalParams{
 			Number:    number,
 			UserLogin: login,
-			Sum:       pgtype.Float4{Float32: float32(sum), Valid: true},
+			Sum:       moneyToPgNumeric(sum),
 		}); err != nil {
 			if strings.Contains(err.Error(), "duplicate key value") {
 				return fmt.Errorf("order number is already exist")
@@ -82,10 +99,15 @@ func (r *orderRepository) Withdraw(ctx context.Context, login, number string, su
 			return fmt.Errorf("upload withdrawal error: %w", err)
 		}
 
-		return qtx.UpdateUserBalance(ctx, gen.UpdateUserBalanceParams{
-			Login:     login,
-			Current:   pgtype.Float4{Float32: -float32(sum), Valid: true},
-			Withdrawn: pgtype.Float4{Float32: float32(sum), Valid: true},
+		if err := transfer(ctx, qtx, login, models.LedgerOperationWITHDRAWAL, number,
+			models.LedgerAccountCurrent, models.LedgerAccountWithdrawn, sum); err != nil {
+			return err
+		}
+
+		return r.addEvent(ctx, qtx, models.OutboxEventWithdrawal, number, models.WithdrawalEvent{
+			Order:     number,
+			UserLogin: login,
+			Sum:       sum,
 		})
 	})
 
@@ -113,15 +135,6 @@ func (r *orderRepository) GetOrdersWithStatus(ctx context.Context, status string
 	return dbToModelOrders(&dbOrders)
 }
 
-func (r *orderRepository) GetUpprocessedOrders(ctx context.Context) (*[]models.Order, error) {
-	dbOrders, err := r.q.GetUnprocessedOrders(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("get unprocessed orders error: %w", err)
-	}
-
-	return dbToModelOrders(&dbOrders)
-}
-
 func (r *orderRepository) GetUserWithdrawals(ctx context.Context, login string) (*[]models.Withdrawal, error) {
 	dbOrders, err := r.q.GetUserWithdrawals(ctx, login)
 	if err != nil {
@@ -132,59 +145,70 @@ func (r *orderRepository) GetUserWithdrawals(ctx context.Context, login string)
 }
 
 func (r *orderRepository) UpdateOrderStatus(ctx context.Context, number, status string) error {
-	err := r.q.UpdateOrderStatus(ctx, gen.UpdateOrderStatusParams{
-		Number: number,
+	if err := transitionOrderStatus(ctx, r.q, number, status); err != nil {
+		return fmt.Errorf("update order status db error: %w", err)
+	}
+	return nil
+}
+
+// transitionOrderStatus changes status only if current one may move to it, see models.CanTransitionOrder.
+// The condition is checked by UPDATE itself, so concurrent updates of the same order can not both pass.
+func transitionOrderStatus(ctx context.Context, q *gen.Queries, number, status string) error {
+	updated, err := q.UpdateOrderStatus(ctx, gen.UpdateOrderStatusParams{
 		Status: pgtype.Text{
 			String: status,
 			Valid:  true,
 		},
+		Number:       number,
+		FromStatuses: models.OrderStatusesBefore(status),
 	})
-
 	if err != nil {
-		return fmt.Errorf("update order status db error: %w", err)
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("order %s to %s: %w", number, status, errs.ErrIllegalOrderTransition)
 	}
 	return nil
 }
 
-func (r *orderRepository) OrderProcessed(ctx context.Context, login, number string, accrual float64) error {
+func (r *orderRepository) OrderProcessed(ctx context.Context, login, number string, accrual models.Money) error {
 	err := withTransaction(ctx, r.db, func(qtx *gen.Queries) error {
+		// Rejected transition also means accrual of the order has already been credited
+		if err := transitionOrderStatus(ctx, qtx, number, models.OrderStatusPROCESSED); err != nil {
+			return fmt.Errorf("failed to update order status to PROCESSED: %w", err)
+		}
+
 		if err := qtx.UpdateOrderAccrual(ctx, gen.UpdateOrderAccrualParams{
-			Number: number,
-			Accrual: pgtype.Float4{
-				Float32: float32(accrual),
-				Valid:   true,
-			},
+			Number:  number,
+			Accrual: moneyToPgNumeric(accrual),
 		}); err != nil {
 			return fmt.Errorf("failed to update accrual. Order number: %s", number)
 		}
 
-		if err := qtx.UpdateOrderStatus(ctx, gen.UpdateOrderStatusParams{
-			Number: number,
-			Status: pgtype.Text{
-				String: models.OrderStatusPROCESSED,
-				Valid:  true,
-			},
-		}); err != nil {
-			return fmt.Errorf("failed to update order status to PROCESSED. Order number: %s", number)
+		if err := qtx.DeleteOrderJob(ctx, number); err != nil {
+			return fmt.Errorf("failed to complete order job. Order number: %s", number)
 		}
 
 		if accrual > 0 {
 			if err := qtx.UpdateUserBalance(ctx, gen.UpdateUserBalanceParams{
-				Login: login,
-				Current: pgtype.Float4{
-					Float32: float32(accrual),
-					Valid:   true,
-				},
-				Withdrawn: pgtype.Float4{
-					Float32: float32(0),
-					Valid:   true,
-				},
+				Login:     login,
+				Current:   moneyToPgNumeric(accrual),
+				Withdrawn: moneyToPgNumeric(0),
 			}); err != nil {
 				return fmt.Errorf("failed to update user balance. User login: %s", login)
 			}
+
+			if err := transfer(ctx, qtx, login, models.LedgerOperationACCRUAL, number,
+				models.LedgerAccountAccrual, models.LedgerAccountCurrent, accrual); err != nil {
+				return err
+			}
 		}
 
-		return nil
+		return r.addEvent(ctx, qtx, models.OutboxEventOrderProcessed, number, models.OrderProcessedEvent{
+			Number:    number,
+			UserLogin: login,
+			Accrual:   accrual,
+		})
 	})
 
 	if err != nil {
@@ -229,3 +253,28 @@ func (r *orderRepository) GetOrderByNumber(ctx context.Context, number string) (
 
 	return dbToModelOrder(&order)
 }
+
+// ParkOrder takes order out of processing queue until an operator looks at it
+func (r *orderRepository) ParkOrder(ctx context.Context, number, reason string) error {
+	err := withTransaction(ctx, r.db, func(qtx *gen.Queries) error {
+		if err := qtx.ParkOrder(ctx, gen.ParkOrderParams{
+			Number: number,
+			Reason: reason,
+		}); err != nil {
+			return err
+		}
+		return qtx.DeleteOrderJob(ctx, number)
+	})
+	if err != nil {
+		return fmt.Errorf("park order db error: %w", err)
+	}
+	return nil
+}
+
+// addEvent writes outbox event in the transaction of qtx if events are recorded
+func (r *orderRepository) addEvent(ctx context.Context, qtx *gen.Queries, eventType, key string, payload any) error {
+	if !r.events {
+		return nil
+	}
+	return addOutboxEvent(ctx, qtx, eventType, key, payload)
+}
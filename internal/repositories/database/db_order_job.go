@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+)
+
+// OrderJobRepository is a queue of orders waiting for accrual. Jobs are added together with orders
+// and removed together with final order status, see orderRepository.
+type OrderJobRepository interface {
+	ClaimOrderJobs(ctx context.Context, worker string, batchSize, leaseSeconds int) (*[]models.OrderJob, error)
+	RescheduleOrderJob(ctx context.Context, number string, delaySeconds int) error
+	ExtendOrderJobLeases(ctx context.Context, worker string, leaseSeconds int) error
+	CompleteOrderJob(ctx context.Context, number string) error
+	GetUserOrdersProcessing(ctx context.Context, login string) (*[]models.OrderProcessing, error)
+	GetParkedOrders(ctx context.Context) (*[]models.OrderProcessing, error)
+}
+
+type orderJobRepository struct {
+	q *gen.Queries
+}
+
+func NewOrderJobRepository(q *gen.Queries) OrderJobRepository {
+	return &orderJobRepository{q: q}
+}
+
+// ClaimOrderJobs leases due jobs to worker. Jobs locked by other replicas are skipped,
+// jobs of a worker that died become due again when lease expires.
+func (r *orderJobRepository) ClaimOrderJobs(ctx context.Context, worker string, batchSize, leaseSeconds int) (*[]models.OrderJob, error) {
+	rows, err := r.q.ClaimOrderJobs(ctx, gen.ClaimOrderJobsParams{
+		BatchSize:    int32(batchSize),
+		LeaseSeconds: int32(leaseSeconds),
+		Worker:       worker,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim order jobs db error: %w", err)
+	}
+
+	return dbToModelOrderJobs(&rows)
+}
+
+// RescheduleOrderJob releases lease and makes job due after delay
+func (r *orderJobRepository) RescheduleOrderJob(ctx context.Context, number string, delaySeconds int) error {
+	err := r.q.RescheduleOrderJob(ctx, gen.RescheduleOrderJobParams{
+		DelaySeconds: int32(delaySeconds),
+		OrderNumber:  number,
+	})
+	if err != nil {
+		return fmt.Errorf("reschedule order job db error: %w", err)
+	}
+	return nil
+}
+
+// ExtendOrderJobLeases prolongs leases of all jobs held by worker. Released jobs have no owner,
+// jobs taken over by other replicas after lease expiry have another one.
+func (r *orderJobRepository) ExtendOrderJobLeases(ctx context.Context, worker string, leaseSeconds int) error {
+	err := r.q.ExtendOrderJobLeases(ctx, gen.ExtendOrderJobLeasesParams{
+		LeaseSeconds: int32(leaseSeconds),
+		Worker:       worker,
+	})
+	if err != nil {
+		return fmt.Errorf("extend order job leases db error: %w", err)
+	}
+	return nil
+}
+
+func (r *orderJobRepository) CompleteOrderJob(ctx context.Context, number string) error {
+	if err := r.q.DeleteOrderJob(ctx, number); err != nil {
+		return fmt.Errorf("complete order job db error: %w", err)
+	}
+	return nil
+}
+
+func (r *orderJobRepository) GetUserOrdersProcessing(ctx context.Context, login string) (*[]models.OrderProcessing, error) {
+	rows, err := r.q.GetUserOrdersProcessing(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("get user orders processing db error: %w", err)
+	}
+
+	return dbToModelOrdersProcessing(&rows)
+}
+
+func (r *orderJobRepository) GetParkedOrders(ctx context.Context) (*[]models.OrderProcessing, error) {
+	rows, err := r.q.GetParkedOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get parked orders db error: %w", err)
+	}
+
+	// Both queries select the same columns
+	converted := make([]gen.GetUserOrdersProcessingRow, len(rows))
+	for i, row := range rows {
+		converted[i] = gen.GetUserOrdersProcessingRow(row)
+	}
+
+	return dbToModelOrdersProcessing(&converted)
+}
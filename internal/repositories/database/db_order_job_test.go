@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func claimedNumbers(jobs *[]models.OrderJob) map[string]int {
+	numbers := make(map[string]int, len(*jobs))
+	for _, j := range *jobs {
+		numbers[j.Order.Number] = j.Attempts
+	}
+	return numbers
+}
+
+func TestOrderJobQueue(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	q := gen.New(db)
+	users := NewUserRepository(q, db)
+	orders := NewOrderRepository(q, db, false)
+	jobs := NewOrderJobRepository(q)
+
+	suffix := time.Now().UnixNano()
+	login := fmt.Sprintf("queue_%d", suffix)
+	require.NoError(t, users.RegisterUser(ctx, models.User{Login: login}))
+
+	number := fmt.Sprintf("%d", suffix)
+	_, err := orders.UploadOrder(ctx, login, number)
+	require.NoError(t, err)
+
+	first, err := jobs.ClaimOrderJobs(ctx, "first", 1000, 60)
+	require.NoError(t, err)
+	assert.Equal(t, 1, claimedNumbers(first)[number], "uploaded order is due at once")
+	for _, j := range *first {
+		if j.Order.Number == number {
+			assert.Less(t, j.Age, time.Minute, "age is measured by database clock")
+		}
+	}
+
+	second, err := jobs.ClaimOrderJobs(ctx, "second", 1000, 60)
+	require.NoError(t, err)
+	assert.NotContains(t, claimedNumbers(second), number, "leased order is hidden from other workers")
+
+	require.NoError(t, jobs.RescheduleOrderJob(ctx, number, 0))
+	again, err := jobs.ClaimOrderJobs(ctx, "second", 1000, 60)
+	require.NoError(t, err)
+	assert.Equal(t, 2, claimedNumbers(again)[number])
+
+	require.NoError(t, orders.OrderProcessed(ctx, login, number, models.NewMoney(10, 0)))
+	require.NoError(t, jobs.RescheduleOrderJob(ctx, number, 0))
+	done, err := jobs.ClaimOrderJobs(ctx, "first", 1000, 60)
+	require.NoError(t, err)
+	assert.NotContains(t, claimedNumbers(done), number, "processed order leaves queue")
+}
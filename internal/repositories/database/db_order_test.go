@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+	"github.com/morzisorn/gofermart/internal/repositories/database/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB connects to TEST_DATABASE_URI and applies migrations
+func newTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	uri := os.Getenv("TEST_DATABASE_URI")
+	if uri == "" {
+		t.Skip("TEST_DATABASE_URI is not set")
+	}
+
+	ctx := context.Background()
+
+	db, err := pgxpool.New(ctx, uri)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+
+	m, err := migrations.NewMigrator(db)
+	require.NoError(t, err)
+	require.NoError(t, m.Up(ctx))
+
+	return db
+}
+
+func TestConcurrentWithdrawals(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	q := gen.New(db)
+	users := NewUserRepository(q, db)
+	orders := NewOrderRepository(q, db, false)
+
+	suffix := time.Now().UnixNano()
+	login := fmt.Sprintf("withdraw_%d", suffix)
+	require.NoError(t, users.RegisterUser(ctx, models.User{Login: login}))
+
+	number := fmt.Sprintf("%d", suffix)
+	_, err := orders.UploadOrder(ctx, login, number)
+	require.NoError(t, err)
+	require.NoError(t, orders.OrderProcessed(ctx, login, number, models.NewMoney(100, 0)))
+
+	const attempts = 10
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := orders.Withdraw(ctx, login, fmt.Sprintf("%d%d", suffix, i), models.NewMoney(30, 0))
+			if err != nil {
+				assert.True(t, errors.Is(err, errs.ErrInsufficientBalance), err)
+				return
+			}
+
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, succeeded)
+
+	user, err := users.GetUser(ctx, login)
+	require.NoError(t, err)
+	assert.Equal(t, models.NewMoney(10, 0), user.Current)
+	assert.Equal(t, models.NewMoney(90, 0), user.Withdrawn)
+}
+
+func TestOrderStatusTransitions(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	q := gen.New(db)
+	users := NewUserRepository(q, db)
+	orders := NewOrderRepository(q, db, false)
+
+	suffix := time.Now().UnixNano()
+	login := fmt.Sprintf("transition_%d", suffix)
+	require.NoError(t, users.RegisterUser(ctx, models.User{Login: login}))
+
+	number := fmt.Sprintf("%d", suffix)
+	_, err := orders.UploadOrder(ctx, login, number)
+	require.NoError(t, err)
+
+	require.NoError(t, orders.UpdateOrderStatus(ctx, number, models.OrderStatusPROCESSING))
+
+	err = orders.UpdateOrderStatus(ctx, number, models.OrderStatusNEW)
+	assert.ErrorIs(t, err, errs.ErrIllegalOrderTransition)
+
+	require.NoError(t, orders.OrderProcessed(ctx, login, number, models.NewMoney(10, 0)))
+
+	err = orders.OrderProcessed(ctx, login, number, models.NewMoney(10, 0))
+	assert.ErrorIs(t, err, errs.ErrIllegalOrderTransition, "accrual is credited once")
+
+	err = orders.UpdateOrderStatus(ctx, number, models.OrderStatusINVALID)
+	assert.ErrorIs(t, err, errs.ErrIllegalOrderTransition)
+
+	user, err := users.GetUser(ctx, login)
+	require.NoError(t, err)
+	assert.Equal(t, models.NewMoney(10, 0), user.Current)
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+)
+
+// OutboxRepository hands events to relay. Events are written by the transactions
+// that make the change, see addOutboxEvent.
+type OutboxRepository interface {
+	ClaimOutboxEvents(ctx context.Context, batchSize, leaseSeconds int) (*[]models.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	FailOutboxEvent(ctx context.Context, id int64, delaySeconds int, reason string) error
+	DeletePublishedOutboxEvents(ctx context.Context, retentionSeconds int) error
+}
+
+type outboxRepository struct {
+	q *gen.Queries
+}
+
+func NewOutboxRepository(q *gen.Queries) OutboxRepository {
+	return &outboxRepository{q: q}
+}
+
+// addOutboxEvent must be called with queries of the transaction that makes the change
+func addOutboxEvent(ctx context.Context, qtx *gen.Queries, eventType, key string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s event error: %w", eventType, err)
+	}
+
+	if err := qtx.CreateOutboxEvent(ctx, gen.CreateOutboxEventParams{
+		EventType:   eventType,
+		AggregateID: key,
+		Payload:     data,
+	}); err != nil {
+		return fmt.Errorf("create %s event error: %w", eventType, err)
+	}
+	return nil
+}
+
+// ClaimOutboxEvents leases due events in creation order, events leased by other replicas are skipped
+func (r *outboxRepository) ClaimOutboxEvents(ctx context.Context, batchSize, leaseSeconds int) (*[]models.OutboxEvent, error) {
+	rows, err := r.q.ClaimOutboxEvents(ctx, gen.ClaimOutboxEventsParams{
+		BatchSize:    int32(batchSize),
+		LeaseSeconds: int32(leaseSeconds),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events db error: %w", err)
+	}
+
+	// UPDATE ... RETURNING does not keep order of the subquery
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+
+	return dbToModelOutboxEvents(&rows)
+}
+
+func (r *outboxRepository) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	if err := r.q.MarkOutboxEventPublished(ctx, id); err != nil {
+		return fmt.Errorf("mark outbox event published db error: %w", err)
+	}
+	return nil
+}
+
+// FailOutboxEvent releases lease and makes event due again after delay
+func (r *outboxRepository) FailOutboxEvent(ctx context.Context, id int64, delaySeconds int, reason string) error {
+	err := r.q.FailOutboxEvent(ctx, gen.FailOutboxEventParams{
+		DelaySeconds: int32(delaySeconds),
+		LastError:    pgtype.Text{String: reason, Valid: true},
+		ID:           id,
+	})
+	if err != nil {
+		return fmt.Errorf("fail outbox event db error: %w", err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) DeletePublishedOutboxEvents(ctx context.Context, retentionSeconds int) error {
+	if err := r.q.DeletePublishedOutboxEvents(ctx, int32(retentionSeconds)); err != nil {
+		return fmt.Errorf("delete published outbox events db error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxEventsWrittenWithChanges(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	q := gen.New(db)
+	users := NewUserRepository(q, db)
+	orders := NewOrderRepository(q, db, true)
+	outbox := NewOutboxRepository(q)
+
+	suffix := time.Now().UnixNano()
+	login := fmt.Sprintf("outbox_%d", suffix)
+	require.NoError(t, users.RegisterUser(ctx, models.User{Login: login}))
+
+	number := fmt.Sprintf("%d", suffix)
+	_, err := orders.UploadOrder(ctx, login, number)
+	require.NoError(t, err)
+	require.NoError(t, orders.OrderProcessed(ctx, login, number, models.NewMoney(100, 0)))
+
+	withdrawal := fmt.Sprintf("%d1", suffix)
+	require.NoError(t, orders.Withdraw(ctx, login, withdrawal, models.NewMoney(30, 0)))
+
+	events, err := outbox.ClaimOutboxEvents(ctx, 1000, 60)
+	require.NoError(t, err)
+
+	found := make(map[string]models.OutboxEvent)
+	for _, e := range *events {
+		if e.Key == number || e.Key == withdrawal {
+			found[e.Type] = e
+		}
+		require.NoError(t, outbox.MarkOutboxEventPublished(ctx, e.ID))
+	}
+
+	require.Contains(t, found, models.OutboxEventOrderProcessed)
+	var processed models.OrderProcessedEvent
+	require.NoError(t, json.Unmarshal(found[models.OutboxEventOrderProcessed].Payload, &processed))
+	assert.Equal(t, models.OrderProcessedEvent{Number: number, UserLogin: login, Accrual: models.NewMoney(100, 0)}, processed)
+
+	require.Contains(t, found, models.OutboxEventWithdrawal)
+	var withdrawn models.WithdrawalEvent
+	require.NoError(t, json.Unmarshal(found[models.OutboxEventWithdrawal].Payload, &withdrawn))
+	assert.Equal(t, models.WithdrawalEvent{Order: withdrawal, UserLogin: login, Sum: models.NewMoney(30, 0)}, withdrawn)
+
+	assert.True(t, found[models.OutboxEventOrderProcessed].ID < found[models.OutboxEventWithdrawal].ID)
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, id, login string, refreshHash []byte, ttlSeconds int) error
+	GetSessionByRefreshHash(ctx context.Context, refreshHash []byte) (*models.Session, error)
+	RotateSession(ctx context.Context, id string, refreshHash, newRefreshHash []byte, ttlSeconds int) (bool, error)
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, login, exceptID string) error
+	IsSessionActive(ctx context.Context, id string) (bool, error)
+	DeleteStaleSessions(ctx context.Context) (int64, error)
+}
+
+type sessionRepository struct {
+	q *gen.Queries
+}
+
+func NewSessionRepository(q *gen.Queries) SessionRepository {
+	return &sessionRepository{q: q}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, id, login string, refreshHash []byte, ttlSeconds int) error {
+	err := r.q.CreateSession(ctx, gen.CreateSessionParams{
+		ID:          id,
+		UserLogin:   login,
+		RefreshHash: refreshHash,
+		TtlSeconds:  int32(ttlSeconds),
+	})
+	if err != nil {
+		return fmt.Errorf("create session db error: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) GetSessionByRefreshHash(ctx context.Context, refreshHash []byte) (*models.Session, error) {
+	s, err := r.q.GetSessionByRefreshHash(ctx, refreshHash)
+	if err != nil {
+		return nil, fmt.Errorf("get session db error: %w", err)
+	}
+
+	return &models.Session{
+		ID:          s.ID,
+		UserLogin:   s.UserLogin,
+		RefreshHash: s.RefreshHash,
+		Revoked:     s.Revoked,
+		Expired:     s.Expired,
+	}, nil
+}
+
+// RotateSession replaces refresh token hash if it is still the current one
+func (r *sessionRepository) RotateSession(ctx context.Context, id string, refreshHash, newRefreshHash []byte, ttlSeconds int) (bool, error) {
+	rotated, err := r.q.RotateSession(ctx, gen.RotateSessionParams{
+		NewRefreshHash: newRefreshHash,
+		TtlSeconds:     int32(ttlSeconds),
+		ID:             id,
+		RefreshHash:    refreshHash,
+	})
+	if err != nil {
+		return false, fmt.Errorf("rotate session db error: %w", err)
+	}
+	return rotated == 1, nil
+}
+
+func (r *sessionRepository) RevokeSession(ctx context.Context, id string) error {
+	if err := r.q.RevokeSession(ctx, id); err != nil {
+		return fmt.Errorf("revoke session db error: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, login, exceptID string) error {
+	err := r.q.RevokeUserSessions(ctx, gen.RevokeUserSessionsParams{
+		UserLogin: login,
+		ID:        exceptID,
+	})
+	if err != nil {
+		return fmt.Errorf("revoke user sessions db error: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) IsSessionActive(ctx context.Context, id string) (bool, error) {
+	active, err := r.q.IsSessionActive(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("check session db error: %w", err)
+	}
+	return active, nil
+}
+
+// DeleteStaleSessions removes expired and revoked sessions. Tokens of a missing session
+// are rejected the same way as of a revoked one.
+func (r *sessionRepository) DeleteStaleSessions(ctx context.Context) (int64, error) {
+	deleted, err := r.q.DeleteStaleSessions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("delete stale sessions db error: %w", err)
+	}
+	return deleted, nil
+}
@@ -2,8 +2,12 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/morzisorn/gofermart/internal/errs"
 	"github.com/morzisorn/gofermart/internal/models"
 	database "github.com/morzisorn/gofermart/internal/repositories/database/generated"
 )
@@ -11,20 +15,27 @@ import (
 type UserRepository interface {
 	RegisterUser(ctx context.Context, user models.User) error
 	GetUser(ctx context.Context, login string) (*models.User, error)
+	UpdateUserPassword(ctx context.Context, login string, password []byte) error
+	DeleteUser(ctx context.Context, login, anonymousLogin string, forfeit bool) error
+	SetUserRole(ctx context.Context, login, role string) error
 }
 
 type userRepository struct {
-	q *database.Queries
+	q  *database.Queries
+	db *pgxpool.Pool
 }
 
-func NewUserRepository(q *database.Queries) UserRepository {
-	return &userRepository{q: q}
+func NewUserRepository(q *database.Queries, db *pgxpool.Pool) UserRepository {
+	return &userRepository{
+		q:  q,
+		db: db,
+	}
 }
 
 func (r *userRepository) RegisterUser(ctx context.Context, user models.User) error {
 	return r.q.RegisterUser(ctx, database.RegisterUserParams{
 		Login:    user.Login,
-		Password: user.Password[:],
+		Password: user.Password,
 	})
 }
 
@@ -34,20 +45,120 @@ func (r *userRepository) GetUser(ctx context.Context, login string) (*models.Use
 		return nil, fmt.Errorf("get db user error: %w", err)
 	}
 
-	current, err := pgxFloat4ToFloat64(u.Current)
+	current, err := pgNumericToMoney(u.Current)
 	if err != nil {
 		return nil, fmt.Errorf("get db user error: %w", err)
 	}
 
-	withdrawn, err := pgxFloat4ToFloat64(u.Withdrawn)
+	withdrawn, err := pgNumericToMoney(u.Withdrawn)
 	if err != nil {
 		return nil, fmt.Errorf("get db user error: %w", err)
 	}
 
 	return &models.User{
 		Login:     u.Login,
-		Password:  [32]byte(u.Password),
+		Password:  u.Password,
 		Current:   current,
 		Withdrawn: withdrawn,
+		Role:      u.Role,
 	}, nil
 }
+
+func (r *userRepository) UpdateUserPassword(ctx context.Context, login string, password []byte) error {
+	err := r.q.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		Login:    login,
+		Password: password,
+	})
+	if err != nil {
+		return fmt.Errorf("update user password db error: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepository) SetUserRole(ctx context.Context, login, role string) error {
+	updated, err := r.q.SetUserRole(ctx, database.SetUserRoleParams{
+		Login: login,
+		Role:  role,
+	})
+	if err != nil {
+		return fmt.Errorf("set user role db error: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("set user role db error: %w", errs.ErrUserNotFound)
+	}
+	return nil
+}
+
+// DeleteUser closes account: sessions are revoked, stored idempotent responses removed and
+// login is replaced with anonymousLogin in the user row and, by cascade, in orders,
+// withdrawals and ledger. Positive balance is moved to forfeited account only if forfeit is set.
+func (r *userRepository) DeleteUser(ctx context.Context, login, anonymousLogin string, forfeit bool) error {
+	err := withTransaction(ctx, r.db, func(qtx *database.Queries) error {
+		dbCurrent, err := qtx.LockActiveUser(ctx, login)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errs.ErrUserNotFound
+			}
+			return fmt.Errorf("lock user error: %w", err)
+		}
+
+		current, err := pgNumericToMoney(dbCurrent)
+		if err != nil {
+			return err
+		}
+
+		if current > 0 {
+			if !forfeit {
+				return errs.ErrPositiveBalance
+			}
+
+			if err := qtx.ForfeitUserBalance(ctx, login); err != nil {
+				return fmt.Errorf("forfeit user balance error: %w", err)
+			}
+
+			if err := transfer(ctx, qtx, login, models.LedgerOperationFORFEIT, anonymousLogin,
+				models.LedgerAccountCurrent, models.LedgerAccountForfeited, current); err != nil {
+				return err
+			}
+		}
+
+		if err := qtx.RevokeUserSessions(ctx, database.RevokeUserSessionsParams{UserLogin: login}); err != nil {
+			return fmt.Errorf("revoke user sessions error: %w", err)
+		}
+
+		if err := qtx.DeleteUserIdempotencyKeys(ctx, login); err != nil {
+			return fmt.Errorf("delete idempotency keys error: %w", err)
+		}
+
+		// Opening entries of balances from before the ledger reference the login itself,
+		// cascade from users changes only user_login
+		if err := qtx.AnonymizeLedgerReferences(ctx, database.AnonymizeLedgerReferencesParams{
+			AnonymousLogin: anonymousLogin,
+			Login:          login,
+		}); err != nil {
+			return fmt.Errorf("anonymize ledger references error: %w", err)
+		}
+
+		// Pending and retained events must not carry the login to subscribers after deletion
+		if err := qtx.AnonymizeOutboxEvents(ctx, database.AnonymizeOutboxEventsParams{
+			AnonymousLogin: anonymousLogin,
+			Login:          login,
+		}); err != nil {
+			return fmt.Errorf("anonymize outbox events error: %w", err)
+		}
+
+		if err := qtx.AnonymizeUser(ctx, database.AnonymizeUserParams{
+			AnonymousLogin: anonymousLogin,
+			Login:          login,
+		}); err != nil {
+			return fmt.Errorf("anonymize user error: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("delete user db error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/models"
+	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteUser(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	q := gen.New(db)
+	users := NewUserRepository(q, db)
+	orders := NewOrderRepository(q, db, true)
+
+	suffix := time.Now().UnixNano()
+	login := fmt.Sprintf("delete_%d", suffix)
+	anonymous := fmt.Sprintf("deleted_%d", suffix)
+	require.NoError(t, users.RegisterUser(ctx, models.User{Login: login}))
+
+	number := fmt.Sprintf("%d", suffix)
+	_, err := orders.UploadOrder(ctx, login, number)
+	require.NoError(t, err)
+	require.NoError(t, orders.OrderProcessed(ctx, login, number, models.NewMoney(10, 0)))
+
+	// Opening entry as written by the baseline migration for balances from before the ledger
+	_, err = db.Exec(ctx, `INSERT INTO ledger_entries (user_login, operation, reference, account, amount)
+		VALUES ($1, 'OPENING', $1, 'opening', 0)`, login)
+	require.NoError(t, err)
+
+	err = users.DeleteUser(ctx, login, anonymous, false)
+	assert.True(t, errors.Is(err, errs.ErrPositiveBalance), err)
+
+	require.NoError(t, users.DeleteUser(ctx, login, anonymous, true))
+
+	_, err = users.GetUser(ctx, login)
+	assert.True(t, errors.Is(err, pgx.ErrNoRows), err)
+
+	order, err := orders.GetOrderByNumber(ctx, number)
+	require.NoError(t, err)
+	assert.Equal(t, anonymous, order.UserLogin)
+
+	balance, err := NewLedgerRepository(q).GetLedgerBalance(ctx, anonymous)
+	require.NoError(t, err)
+	assert.Equal(t, models.Money(0), balance.Current)
+
+	var left int
+	require.NoError(t, db.QueryRow(ctx,
+		`SELECT count(*) FROM ledger_entries WHERE user_login = $1 OR reference = $1`, login).Scan(&left))
+	assert.Zero(t, left, "ledger must not keep the deleted login")
+
+	require.NoError(t, db.QueryRow(ctx,
+		`SELECT count(*) FROM outbox_events WHERE payload->>'user_login' = $1`, login).Scan(&left))
+	assert.Zero(t, left, "outbox events must not keep the deleted login")
+}
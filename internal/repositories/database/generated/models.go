@@ -8,24 +8,102 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type BalanceAdjustment struct {
+	ID            int64            `json:"id"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UserLogin     string           `json:"user_login"`
+	Amount        pgtype.Numeric   `json:"amount"`
+	Reason        string           `json:"reason"`
+	Note          string           `json:"note"`
+	OperatorLogin string           `json:"operator_login"`
+}
+
+type IdempotencyKey struct {
+	UserLogin    string           `json:"user_login"`
+	Key          string           `json:"key"`
+	RequestHash  []byte           `json:"request_hash"`
+	StatusCode   pgtype.Int4      `json:"status_code"`
+	ContentType  pgtype.Text      `json:"content_type"`
+	ResponseBody []byte           `json:"response_body"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	LockedUntil  pgtype.Timestamp `json:"locked_until"`
+}
+
+type LedgerEntry struct {
+	ID        int64            `json:"id"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	UserLogin string           `json:"user_login"`
+	Operation string           `json:"operation"`
+	Reference string           `json:"reference"`
+	Account   string           `json:"account"`
+	Amount    pgtype.Numeric   `json:"amount"`
+}
+
+type LoginAttempt struct {
+	Key           string           `json:"key"`
+	Failures      int32            `json:"failures"`
+	LastFailureAt pgtype.Timestamp `json:"last_failure_at"`
+	LockedUntil   pgtype.Timestamp `json:"locked_until"`
+}
+
 type Order struct {
 	Number     string           `json:"number"`
 	UploadedAt pgtype.Timestamp `json:"uploaded_at"`
 	UserLogin  string           `json:"user_login"`
 	Status     pgtype.Text      `json:"status"`
-	Accrual    pgtype.Float4    `json:"accrual"`
+	Accrual    pgtype.Numeric   `json:"accrual"`
+}
+
+type OrderJob struct {
+	OrderNumber   string           `json:"order_number"`
+	NextAttemptAt pgtype.Timestamp `json:"next_attempt_at"`
+	Attempts      int32            `json:"attempts"`
+	LockedUntil   pgtype.Timestamp `json:"locked_until"`
+	LockedBy      pgtype.Text      `json:"locked_by"`
+	LastCheckedAt pgtype.Timestamp `json:"last_checked_at"`
+}
+
+type OutboxEvent struct {
+	ID            int64            `json:"id"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	EventType     string           `json:"event_type"`
+	AggregateID   string           `json:"aggregate_id"`
+	Payload       []byte           `json:"payload"`
+	Attempts      int32            `json:"attempts"`
+	NextAttemptAt pgtype.Timestamp `json:"next_attempt_at"`
+	LockedUntil   pgtype.Timestamp `json:"locked_until"`
+	LastError     pgtype.Text      `json:"last_error"`
+	PublishedAt   pgtype.Timestamp `json:"published_at"`
+}
+
+type ParkedOrder struct {
+	Number   string           `json:"number"`
+	ParkedAt pgtype.Timestamp `json:"parked_at"`
+	Reason   string           `json:"reason"`
+}
+
+type Session struct {
+	ID                  string           `json:"id"`
+	UserLogin           string           `json:"user_login"`
+	RefreshHash         []byte           `json:"refresh_hash"`
+	PreviousRefreshHash []byte           `json:"previous_refresh_hash"`
+	CreatedAt           pgtype.Timestamp `json:"created_at"`
+	ExpiresAt           pgtype.Timestamp `json:"expires_at"`
+	RevokedAt           pgtype.Timestamp `json:"revoked_at"`
 }
 
 type User struct {
-	Login     string        `json:"login"`
-	Password  []byte        `json:"password"`
-	Current   pgtype.Float4 `json:"current"`
-	Withdrawn pgtype.Float4 `json:"withdrawn"`
+	Login     string           `json:"login"`
+	Password  []byte           `json:"password"`
+	Current   pgtype.Numeric   `json:"current"`
+	Withdrawn pgtype.Numeric   `json:"withdrawn"`
+	DeletedAt pgtype.Timestamp `json:"deleted_at"`
+	Role      string           `json:"role"`
 }
 
 type Withdrawal struct {
 	Number      string           `json:"number"`
 	ProcessedAt pgtype.Timestamp `json:"processed_at"`
 	UserLogin   string           `json:"user_login"`
-	Sum         pgtype.Float4    `json:"sum"`
+	Sum         pgtype.Numeric   `json:"sum"`
 }
@@ -11,16 +11,60 @@ import (
 )
 
 type Querier interface {
+	AddLedgerEntry(ctx context.Context, arg AddLedgerEntryParams) error
+	AnonymizeLedgerReferences(ctx context.Context, arg AnonymizeLedgerReferencesParams) error
+	AnonymizeOutboxEvents(ctx context.Context, arg AnonymizeOutboxEventsParams) error
+	AnonymizeUser(ctx context.Context, arg AnonymizeUserParams) error
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (int64, error)
+	ClaimOrderJobs(ctx context.Context, arg ClaimOrderJobsParams) ([]ClaimOrderJobsRow, error)
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]ClaimOutboxEventsRow, error)
+	CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error
+	CreateBalanceAdjustment(ctx context.Context, arg CreateBalanceAdjustmentParams) (BalanceAdjustment, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) error
+	DebitUserBalance(ctx context.Context, arg DebitUserBalanceParams) (int64, error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context, arg DeleteExpiredIdempotencyKeysParams) error
+	DeleteOrderJob(ctx context.Context, orderNumber string) error
+	DeletePublishedOutboxEvents(ctx context.Context, retentionSeconds int32) error
+	DeleteStaleLoginAttempts(ctx context.Context, windowSeconds int32) (int64, error)
+	DeleteStaleSessions(ctx context.Context) (int64, error)
+	DeleteUserIdempotencyKeys(ctx context.Context, userLogin string) error
+	EnqueueOrderJob(ctx context.Context, orderNumber string) error
+	ExtendOrderJobLeases(ctx context.Context, arg ExtendOrderJobLeasesParams) error
+	FailOutboxEvent(ctx context.Context, arg FailOutboxEventParams) error
+	ForfeitUserBalance(ctx context.Context, login string) error
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetLedgerBalance(ctx context.Context, userLogin string) (GetLedgerBalanceRow, error)
+	GetLoginLock(ctx context.Context, key string) (int32, error)
 	GetOrderByNumber(ctx context.Context, number string) (Order, error)
 	GetOrdersWithStatus(ctx context.Context, status pgtype.Text) ([]Order, error)
-	GetUnprocessedOrders(ctx context.Context) ([]Order, error)
-	GetUser(ctx context.Context, login string) (User, error)
+	GetParkedOrders(ctx context.Context) ([]GetParkedOrdersRow, error)
+	GetSessionByRefreshHash(ctx context.Context, refreshHash []byte) (GetSessionByRefreshHashRow, error)
+	GetUser(ctx context.Context, login string) (GetUserRow, error)
+	GetUserAdjustments(ctx context.Context, userLogin string) ([]BalanceAdjustment, error)
+	GetUserLedger(ctx context.Context, userLogin string) ([]LedgerEntry, error)
 	GetUserOrders(ctx context.Context, userLogin string) ([]Order, error)
+	GetUserOrdersProcessing(ctx context.Context, userLogin string) ([]GetUserOrdersProcessingRow, error)
 	GetUserWithdrawals(ctx context.Context, userLogin string) ([]Withdrawal, error)
+	IsSessionActive(ctx context.Context, id string) (bool, error)
+	LockActiveUser(ctx context.Context, login string) (pgtype.Numeric, error)
+	LockLoginKey(ctx context.Context, arg LockLoginKeyParams) error
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	ParkOrder(ctx context.Context, arg ParkOrderParams) error
+	RecordLoginAttempt(ctx context.Context, arg RecordLoginAttemptParams) (int32, error)
 	RegisterUser(ctx context.Context, arg RegisterUserParams) error
+	ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error
+	ReleaseLoginAttempt(ctx context.Context, arg ReleaseLoginAttemptParams) error
+	RescheduleOrderJob(ctx context.Context, arg RescheduleOrderJobParams) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, arg RevokeUserSessionsParams) error
+	RotateSession(ctx context.Context, arg RotateSessionParams) (int64, error)
+	SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error)
 	UpdateOrderAccrual(ctx context.Context, arg UpdateOrderAccrualParams) error
-	UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) error
+	UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) (int64, error)
 	UpdateUserBalance(ctx context.Context, arg UpdateUserBalanceParams) error
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
 	UploadOrder(ctx context.Context, arg UploadOrderParams) error
 	UploadWithdrawal(ctx context.Context, arg UploadWithdrawalParams) error
 }
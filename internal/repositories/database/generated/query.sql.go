@@ -11,6 +11,549 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addLedgerEntry = `-- name: AddLedgerEntry :exec
+INSERT INTO ledger_entries (user_login, operation, reference, account, amount)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type AddLedgerEntryParams struct {
+	UserLogin string         `json:"user_login"`
+	Operation string         `json:"operation"`
+	Reference string         `json:"reference"`
+	Account   string         `json:"account"`
+	Amount    pgtype.Numeric `json:"amount"`
+}
+
+func (q *Queries) AddLedgerEntry(ctx context.Context, arg AddLedgerEntryParams) error {
+	_, err := q.db.Exec(ctx, addLedgerEntry,
+		arg.UserLogin,
+		arg.Operation,
+		arg.Reference,
+		arg.Account,
+		arg.Amount,
+	)
+	return err
+}
+
+const anonymizeLedgerReferences = `-- name: AnonymizeLedgerReferences :exec
+UPDATE ledger_entries
+SET reference = $1
+WHERE user_login = $2 AND reference = $2
+`
+
+type AnonymizeLedgerReferencesParams struct {
+	AnonymousLogin string `json:"anonymous_login"`
+	Login          string `json:"login"`
+}
+
+func (q *Queries) AnonymizeLedgerReferences(ctx context.Context, arg AnonymizeLedgerReferencesParams) error {
+	_, err := q.db.Exec(ctx, anonymizeLedgerReferences, arg.AnonymousLogin, arg.Login)
+	return err
+}
+
+const anonymizeOutboxEvents = `-- name: AnonymizeOutboxEvents :exec
+UPDATE outbox_events
+SET payload = jsonb_set(payload, '{user_login}', to_jsonb($1::TEXT))
+WHERE payload->>'user_login' = $2::TEXT
+`
+
+type AnonymizeOutboxEventsParams struct {
+	AnonymousLogin string `json:"anonymous_login"`
+	Login          string `json:"login"`
+}
+
+func (q *Queries) AnonymizeOutboxEvents(ctx context.Context, arg AnonymizeOutboxEventsParams) error {
+	_, err := q.db.Exec(ctx, anonymizeOutboxEvents, arg.AnonymousLogin, arg.Login)
+	return err
+}
+
+const anonymizeUser = `-- name: AnonymizeUser :exec
+UPDATE users
+SET login = $1, password = ''::BYTEA, deleted_at = CURRENT_TIMESTAMP
+WHERE login = $2
+`
+
+type AnonymizeUserParams struct {
+	AnonymousLogin string `json:"anonymous_login"`
+	Login          string `json:"login"`
+}
+
+func (q *Queries) AnonymizeUser(ctx context.Context, arg AnonymizeUserParams) error {
+	_, err := q.db.Exec(ctx, anonymizeUser, arg.AnonymousLogin, arg.Login)
+	return err
+}
+
+const claimIdempotencyKey = `-- name: ClaimIdempotencyKey :execrows
+INSERT INTO idempotency_keys (user_login, key, request_hash, locked_until)
+VALUES (
+    $1,
+    $2,
+    $3,
+    CURRENT_TIMESTAMP + $4::INTEGER * INTERVAL '1 second'
+)
+ON CONFLICT (user_login, key) DO UPDATE
+SET locked_until = EXCLUDED.locked_until, created_at = CURRENT_TIMESTAMP
+WHERE idempotency_keys.status_code IS NULL
+  AND idempotency_keys.request_hash = EXCLUDED.request_hash
+  AND (idempotency_keys.locked_until IS NULL OR idempotency_keys.locked_until < CURRENT_TIMESTAMP)
+`
+
+type ClaimIdempotencyKeyParams struct {
+	UserLogin    string `json:"user_login"`
+	Key          string `json:"key"`
+	RequestHash  []byte `json:"request_hash"`
+	LeaseSeconds int32  `json:"lease_seconds"`
+}
+
+func (q *Queries) ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, claimIdempotencyKey,
+		arg.UserLogin,
+		arg.Key,
+		arg.RequestHash,
+		arg.LeaseSeconds,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const claimOrderJobs = `-- name: ClaimOrderJobs :many
+WITH due AS (
+    SELECT order_number
+    FROM order_jobs
+    WHERE next_attempt_at <= CURRENT_TIMESTAMP
+      AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+    ORDER BY next_attempt_at
+    LIMIT $1::INTEGER
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE order_jobs j
+SET attempts = j.attempts + 1,
+    locked_until = CURRENT_TIMESTAMP + $2::INTEGER * INTERVAL '1 second',
+    locked_by = $3::TEXT
+FROM due, orders o
+WHERE j.order_number = due.order_number AND o.number = j.order_number
+RETURNING o.number, o.uploaded_at, o.user_login, o.status, o.accrual, j.attempts,
+    EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - o.uploaded_at)::INTEGER AS age_seconds
+`
+
+type ClaimOrderJobsParams struct {
+	BatchSize    int32  `json:"batch_size"`
+	LeaseSeconds int32  `json:"lease_seconds"`
+	Worker       string `json:"worker"`
+}
+
+type ClaimOrderJobsRow struct {
+	Number     string           `json:"number"`
+	UploadedAt pgtype.Timestamp `json:"uploaded_at"`
+	UserLogin  string           `json:"user_login"`
+	Status     pgtype.Text      `json:"status"`
+	Accrual    pgtype.Numeric   `json:"accrual"`
+	Attempts   int32            `json:"attempts"`
+	AgeSeconds int32            `json:"age_seconds"`
+}
+
+func (q *Queries) ClaimOrderJobs(ctx context.Context, arg ClaimOrderJobsParams) ([]ClaimOrderJobsRow, error) {
+	rows, err := q.db.Query(ctx, claimOrderJobs, arg.BatchSize, arg.LeaseSeconds, arg.Worker)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimOrderJobsRow
+	for rows.Next() {
+		var i ClaimOrderJobsRow
+		if err := rows.Scan(
+			&i.Number,
+			&i.UploadedAt,
+			&i.UserLogin,
+			&i.Status,
+			&i.Accrual,
+			&i.Attempts,
+			&i.AgeSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimOutboxEvents = `-- name: ClaimOutboxEvents :many
+WITH due AS (
+    SELECT id
+    FROM outbox_events
+    WHERE published_at IS NULL
+      AND next_attempt_at <= CURRENT_TIMESTAMP
+      AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+    ORDER BY id
+    LIMIT $1::INTEGER
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE outbox_events e
+SET attempts = e.attempts + 1,
+    locked_until = CURRENT_TIMESTAMP + $2::INTEGER * INTERVAL '1 second'
+FROM due
+WHERE e.id = due.id
+RETURNING e.id, e.created_at, e.event_type, e.aggregate_id, e.payload, e.attempts
+`
+
+type ClaimOutboxEventsParams struct {
+	BatchSize    int32 `json:"batch_size"`
+	LeaseSeconds int32 `json:"lease_seconds"`
+}
+
+type ClaimOutboxEventsRow struct {
+	ID          int64            `json:"id"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	EventType   string           `json:"event_type"`
+	AggregateID string           `json:"aggregate_id"`
+	Payload     []byte           `json:"payload"`
+	Attempts    int32            `json:"attempts"`
+}
+
+func (q *Queries) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]ClaimOutboxEventsRow, error) {
+	rows, err := q.db.Query(ctx, claimOutboxEvents, arg.BatchSize, arg.LeaseSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimOutboxEventsRow
+	for rows.Next() {
+		var i ClaimOutboxEventsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.EventType,
+			&i.AggregateID,
+			&i.Payload,
+			&i.Attempts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeIdempotencyKey = `-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status_code = $3, content_type = $4, response_body = $5
+WHERE user_login = $1 AND key = $2
+`
+
+type CompleteIdempotencyKeyParams struct {
+	UserLogin    string      `json:"user_login"`
+	Key          string      `json:"key"`
+	StatusCode   pgtype.Int4 `json:"status_code"`
+	ContentType  pgtype.Text `json:"content_type"`
+	ResponseBody []byte      `json:"response_body"`
+}
+
+func (q *Queries) CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, completeIdempotencyKey,
+		arg.UserLogin,
+		arg.Key,
+		arg.StatusCode,
+		arg.ContentType,
+		arg.ResponseBody,
+	)
+	return err
+}
+
+const createBalanceAdjustment = `-- name: CreateBalanceAdjustment :one
+INSERT INTO balance_adjustments (user_login, amount, reason, note, operator_login)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at, user_login, amount, reason, note, operator_login
+`
+
+type CreateBalanceAdjustmentParams struct {
+	UserLogin     string         `json:"user_login"`
+	Amount        pgtype.Numeric `json:"amount"`
+	Reason        string         `json:"reason"`
+	Note          string         `json:"note"`
+	OperatorLogin string         `json:"operator_login"`
+}
+
+func (q *Queries) CreateBalanceAdjustment(ctx context.Context, arg CreateBalanceAdjustmentParams) (BalanceAdjustment, error) {
+	row := q.db.QueryRow(ctx, createBalanceAdjustment,
+		arg.UserLogin,
+		arg.Amount,
+		arg.Reason,
+		arg.Note,
+		arg.OperatorLogin,
+	)
+	var i BalanceAdjustment
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserLogin,
+		&i.Amount,
+		&i.Reason,
+		&i.Note,
+		&i.OperatorLogin,
+	)
+	return i, err
+}
+
+const createOutboxEvent = `-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events (event_type, aggregate_id, payload)
+VALUES ($1, $2, $3)
+`
+
+type CreateOutboxEventParams struct {
+	EventType   string `json:"event_type"`
+	AggregateID string `json:"aggregate_id"`
+	Payload     []byte `json:"payload"`
+}
+
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, createOutboxEvent, arg.EventType, arg.AggregateID, arg.Payload)
+	return err
+}
+
+const createSession = `-- name: CreateSession :exec
+INSERT INTO sessions (id, user_login, refresh_hash, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    CURRENT_TIMESTAMP + $4::INTEGER * INTERVAL '1 second'
+)
+`
+
+type CreateSessionParams struct {
+	ID          string `json:"id"`
+	UserLogin   string `json:"user_login"`
+	RefreshHash []byte `json:"refresh_hash"`
+	TtlSeconds  int32  `json:"ttl_seconds"`
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) error {
+	_, err := q.db.Exec(ctx, createSession,
+		arg.ID,
+		arg.UserLogin,
+		arg.RefreshHash,
+		arg.TtlSeconds,
+	)
+	return err
+}
+
+const debitUserBalance = `-- name: DebitUserBalance :execrows
+UPDATE users
+SET current = current - $1::NUMERIC, withdrawn = withdrawn + $1::NUMERIC
+WHERE login = $2 AND current >= $1::NUMERIC
+`
+
+type DebitUserBalanceParams struct {
+	Amount pgtype.Numeric `json:"amount"`
+	Login  string         `json:"login"`
+}
+
+func (q *Queries) DebitUserBalance(ctx context.Context, arg DebitUserBalanceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, debitUserBalance, arg.Amount, arg.Login)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredIdempotencyKeys = `-- name: DeleteExpiredIdempotencyKeys :exec
+DELETE FROM idempotency_keys
+WHERE user_login = $1
+  AND created_at < CURRENT_TIMESTAMP - $2::INTEGER * INTERVAL '1 second'
+`
+
+type DeleteExpiredIdempotencyKeysParams struct {
+	UserLogin     string `json:"user_login"`
+	WindowSeconds int32  `json:"window_seconds"`
+}
+
+func (q *Queries) DeleteExpiredIdempotencyKeys(ctx context.Context, arg DeleteExpiredIdempotencyKeysParams) error {
+	_, err := q.db.Exec(ctx, deleteExpiredIdempotencyKeys, arg.UserLogin, arg.WindowSeconds)
+	return err
+}
+
+const deleteOrderJob = `-- name: DeleteOrderJob :exec
+DELETE FROM order_jobs
+WHERE order_number = $1
+`
+
+func (q *Queries) DeleteOrderJob(ctx context.Context, orderNumber string) error {
+	_, err := q.db.Exec(ctx, deleteOrderJob, orderNumber)
+	return err
+}
+
+const deletePublishedOutboxEvents = `-- name: DeletePublishedOutboxEvents :exec
+DELETE FROM outbox_events
+WHERE published_at < CURRENT_TIMESTAMP - $1::INTEGER * INTERVAL '1 second'
+`
+
+func (q *Queries) DeletePublishedOutboxEvents(ctx context.Context, retentionSeconds int32) error {
+	_, err := q.db.Exec(ctx, deletePublishedOutboxEvents, retentionSeconds)
+	return err
+}
+
+const deleteStaleLoginAttempts = `-- name: DeleteStaleLoginAttempts :execrows
+DELETE FROM login_attempts
+WHERE last_failure_at < CURRENT_TIMESTAMP - $1::INTEGER * INTERVAL '1 second'
+  AND (locked_until IS NULL OR locked_until < CURRENT_TIMESTAMP)
+`
+
+func (q *Queries) DeleteStaleLoginAttempts(ctx context.Context, windowSeconds int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteStaleLoginAttempts, windowSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteStaleSessions = `-- name: DeleteStaleSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= CURRENT_TIMESTAMP OR revoked_at IS NOT NULL
+`
+
+func (q *Queries) DeleteStaleSessions(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteStaleSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserIdempotencyKeys = `-- name: DeleteUserIdempotencyKeys :exec
+DELETE FROM idempotency_keys
+WHERE user_login = $1
+`
+
+func (q *Queries) DeleteUserIdempotencyKeys(ctx context.Context, userLogin string) error {
+	_, err := q.db.Exec(ctx, deleteUserIdempotencyKeys, userLogin)
+	return err
+}
+
+const enqueueOrderJob = `-- name: EnqueueOrderJob :exec
+INSERT INTO order_jobs (order_number)
+VALUES ($1)
+ON CONFLICT (order_number) DO NOTHING
+`
+
+func (q *Queries) EnqueueOrderJob(ctx context.Context, orderNumber string) error {
+	_, err := q.db.Exec(ctx, enqueueOrderJob, orderNumber)
+	return err
+}
+
+const extendOrderJobLeases = `-- name: ExtendOrderJobLeases :exec
+UPDATE order_jobs
+SET locked_until = CURRENT_TIMESTAMP + $1::INTEGER * INTERVAL '1 second'
+WHERE locked_by = $2::TEXT
+`
+
+type ExtendOrderJobLeasesParams struct {
+	LeaseSeconds int32  `json:"lease_seconds"`
+	Worker       string `json:"worker"`
+}
+
+func (q *Queries) ExtendOrderJobLeases(ctx context.Context, arg ExtendOrderJobLeasesParams) error {
+	_, err := q.db.Exec(ctx, extendOrderJobLeases, arg.LeaseSeconds, arg.Worker)
+	return err
+}
+
+const failOutboxEvent = `-- name: FailOutboxEvent :exec
+UPDATE outbox_events
+SET next_attempt_at = CURRENT_TIMESTAMP + $1::INTEGER * INTERVAL '1 second',
+    locked_until = NULL,
+    last_error = $2
+WHERE id = $3
+`
+
+type FailOutboxEventParams struct {
+	DelaySeconds int32       `json:"delay_seconds"`
+	LastError    pgtype.Text `json:"last_error"`
+	ID           int64       `json:"id"`
+}
+
+func (q *Queries) FailOutboxEvent(ctx context.Context, arg FailOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, failOutboxEvent, arg.DelaySeconds, arg.LastError, arg.ID)
+	return err
+}
+
+const forfeitUserBalance = `-- name: ForfeitUserBalance :exec
+UPDATE users
+SET current = 0
+WHERE login = $1
+`
+
+func (q *Queries) ForfeitUserBalance(ctx context.Context, login string) error {
+	_, err := q.db.Exec(ctx, forfeitUserBalance, login)
+	return err
+}
+
+const getIdempotencyKey = `-- name: GetIdempotencyKey :one
+SELECT user_login, key, request_hash, status_code, content_type, response_body, created_at, locked_until
+FROM idempotency_keys
+WHERE user_login = $1 AND key = $2
+`
+
+type GetIdempotencyKeyParams struct {
+	UserLogin string `json:"user_login"`
+	Key       string `json:"key"`
+}
+
+func (q *Queries) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, getIdempotencyKey, arg.UserLogin, arg.Key)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.UserLogin,
+		&i.Key,
+		&i.RequestHash,
+		&i.StatusCode,
+		&i.ContentType,
+		&i.ResponseBody,
+		&i.CreatedAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getLedgerBalance = `-- name: GetLedgerBalance :one
+SELECT
+    COALESCE(SUM(amount) FILTER (WHERE account = 'current'), 0)::NUMERIC(12, 2) AS current,
+    COALESCE(SUM(amount) FILTER (WHERE account = 'withdrawn'), 0)::NUMERIC(12, 2) AS withdrawn
+FROM ledger_entries
+WHERE user_login = $1
+`
+
+type GetLedgerBalanceRow struct {
+	Current   pgtype.Numeric `json:"current"`
+	Withdrawn pgtype.Numeric `json:"withdrawn"`
+}
+
+func (q *Queries) GetLedgerBalance(ctx context.Context, userLogin string) (GetLedgerBalanceRow, error) {
+	row := q.db.QueryRow(ctx, getLedgerBalance, userLogin)
+	var i GetLedgerBalanceRow
+	err := row.Scan(&i.Current, &i.Withdrawn)
+	return i, err
+}
+
+const getLoginLock = `-- name: GetLoginLock :one
+SELECT GREATEST(CEIL(EXTRACT(EPOCH FROM locked_until - CURRENT_TIMESTAMP)), 0)::INTEGER AS locked_seconds
+FROM login_attempts
+WHERE key = $1 AND locked_until IS NOT NULL
+`
+
+func (q *Queries) GetLoginLock(ctx context.Context, key string) (int32, error) {
+	row := q.db.QueryRow(ctx, getLoginLock, key)
+	var locked_seconds int32
+	err := row.Scan(&locked_seconds)
+	return locked_seconds, err
+}
+
 const getOrderByNumber = `-- name: GetOrderByNumber :one
 SELECT number, uploaded_at, user_login, status, accrual
 FROM orders
@@ -62,27 +605,52 @@ func (q *Queries) GetOrdersWithStatus(ctx context.Context, status pgtype.Text) (
 	return items, nil
 }
 
-const getUnprocessedOrders = `-- name: GetUnprocessedOrders :many
-SELECT number, uploaded_at, user_login, status, accrual
-FROM orders
-WHERE status in ('NEW', 'PROCESSING')
+const getParkedOrders = `-- name: GetParkedOrders :many
+SELECT o.number, o.uploaded_at, o.user_login, o.status, o.accrual,
+    j.attempts, j.next_attempt_at, j.last_checked_at, j.locked_until,
+    p.parked_at, p.reason AS parked_reason
+FROM orders o
+LEFT JOIN order_jobs j ON j.order_number = o.number
+LEFT JOIN parked_orders p ON p.number = o.number
+WHERE p.number IS NOT NULL
+ORDER BY p.parked_at DESC
 `
 
-func (q *Queries) GetUnprocessedOrders(ctx context.Context) ([]Order, error) {
-	rows, err := q.db.Query(ctx, getUnprocessedOrders)
+type GetParkedOrdersRow struct {
+	Number        string           `json:"number"`
+	UploadedAt    pgtype.Timestamp `json:"uploaded_at"`
+	UserLogin     string           `json:"user_login"`
+	Status        pgtype.Text      `json:"status"`
+	Accrual       pgtype.Numeric   `json:"accrual"`
+	Attempts      pgtype.Int4      `json:"attempts"`
+	NextAttemptAt pgtype.Timestamp `json:"next_attempt_at"`
+	LastCheckedAt pgtype.Timestamp `json:"last_checked_at"`
+	LockedUntil   pgtype.Timestamp `json:"locked_until"`
+	ParkedAt      pgtype.Timestamp `json:"parked_at"`
+	ParkedReason  pgtype.Text      `json:"parked_reason"`
+}
+
+func (q *Queries) GetParkedOrders(ctx context.Context) ([]GetParkedOrdersRow, error) {
+	rows, err := q.db.Query(ctx, getParkedOrders)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Order
+	var items []GetParkedOrdersRow
 	for rows.Next() {
-		var i Order
+		var i GetParkedOrdersRow
 		if err := rows.Scan(
 			&i.Number,
 			&i.UploadedAt,
 			&i.UserLogin,
 			&i.Status,
 			&i.Accrual,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastCheckedAt,
+			&i.LockedUntil,
+			&i.ParkedAt,
+			&i.ParkedReason,
 		); err != nil {
 			return nil, err
 		}
@@ -94,24 +662,132 @@ func (q *Queries) GetUnprocessedOrders(ctx context.Context) ([]Order, error) {
 	return items, nil
 }
 
+const getSessionByRefreshHash = `-- name: GetSessionByRefreshHash :one
+SELECT id, user_login, refresh_hash,
+    revoked_at IS NOT NULL AS revoked,
+    expires_at <= CURRENT_TIMESTAMP AS expired
+FROM sessions
+WHERE refresh_hash = $1 OR previous_refresh_hash = $1
+`
+
+type GetSessionByRefreshHashRow struct {
+	ID          string `json:"id"`
+	UserLogin   string `json:"user_login"`
+	RefreshHash []byte `json:"refresh_hash"`
+	Revoked     bool   `json:"revoked"`
+	Expired     bool   `json:"expired"`
+}
+
+func (q *Queries) GetSessionByRefreshHash(ctx context.Context, refreshHash []byte) (GetSessionByRefreshHashRow, error) {
+	row := q.db.QueryRow(ctx, getSessionByRefreshHash, refreshHash)
+	var i GetSessionByRefreshHashRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserLogin,
+		&i.RefreshHash,
+		&i.Revoked,
+		&i.Expired,
+	)
+	return i, err
+}
+
 const getUser = `-- name: GetUser :one
-SELECT login, password, current, withdrawn
+SELECT login, password, current, withdrawn, role
 FROM users
-WHERE login = $1
+WHERE login = $1 AND deleted_at IS NULL
 `
 
-func (q *Queries) GetUser(ctx context.Context, login string) (User, error) {
+type GetUserRow struct {
+	Login     string         `json:"login"`
+	Password  []byte         `json:"password"`
+	Current   pgtype.Numeric `json:"current"`
+	Withdrawn pgtype.Numeric `json:"withdrawn"`
+	Role      string         `json:"role"`
+}
+
+func (q *Queries) GetUser(ctx context.Context, login string) (GetUserRow, error) {
 	row := q.db.QueryRow(ctx, getUser, login)
-	var i User
+	var i GetUserRow
 	err := row.Scan(
 		&i.Login,
 		&i.Password,
 		&i.Current,
 		&i.Withdrawn,
+		&i.Role,
 	)
 	return i, err
 }
 
+const getUserAdjustments = `-- name: GetUserAdjustments :many
+SELECT id, created_at, user_login, amount, reason, note, operator_login
+FROM balance_adjustments
+WHERE user_login = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetUserAdjustments(ctx context.Context, userLogin string) ([]BalanceAdjustment, error) {
+	rows, err := q.db.Query(ctx, getUserAdjustments, userLogin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BalanceAdjustment
+	for rows.Next() {
+		var i BalanceAdjustment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserLogin,
+			&i.Amount,
+			&i.Reason,
+			&i.Note,
+			&i.OperatorLogin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserLedger = `-- name: GetUserLedger :many
+SELECT id, created_at, user_login, operation, reference, account, amount
+FROM ledger_entries
+WHERE user_login = $1
+ORDER BY id
+`
+
+func (q *Queries) GetUserLedger(ctx context.Context, userLogin string) ([]LedgerEntry, error) {
+	rows, err := q.db.Query(ctx, getUserLedger, userLogin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LedgerEntry
+	for rows.Next() {
+		var i LedgerEntry
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserLogin,
+			&i.Operation,
+			&i.Reference,
+			&i.Account,
+			&i.Amount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserOrders = `-- name: GetUserOrders :many
 SELECT number, uploaded_at, user_login, status, accrual
 FROM orders
@@ -145,6 +821,63 @@ func (q *Queries) GetUserOrders(ctx context.Context, userLogin string) ([]Order,
 	return items, nil
 }
 
+const getUserOrdersProcessing = `-- name: GetUserOrdersProcessing :many
+SELECT o.number, o.uploaded_at, o.user_login, o.status, o.accrual,
+    j.attempts, j.next_attempt_at, j.last_checked_at, j.locked_until,
+    p.parked_at, p.reason AS parked_reason
+FROM orders o
+LEFT JOIN order_jobs j ON j.order_number = o.number
+LEFT JOIN parked_orders p ON p.number = o.number
+WHERE o.user_login = $1
+ORDER BY o.uploaded_at DESC
+`
+
+type GetUserOrdersProcessingRow struct {
+	Number        string           `json:"number"`
+	UploadedAt    pgtype.Timestamp `json:"uploaded_at"`
+	UserLogin     string           `json:"user_login"`
+	Status        pgtype.Text      `json:"status"`
+	Accrual       pgtype.Numeric   `json:"accrual"`
+	Attempts      pgtype.Int4      `json:"attempts"`
+	NextAttemptAt pgtype.Timestamp `json:"next_attempt_at"`
+	LastCheckedAt pgtype.Timestamp `json:"last_checked_at"`
+	LockedUntil   pgtype.Timestamp `json:"locked_until"`
+	ParkedAt      pgtype.Timestamp `json:"parked_at"`
+	ParkedReason  pgtype.Text      `json:"parked_reason"`
+}
+
+func (q *Queries) GetUserOrdersProcessing(ctx context.Context, userLogin string) ([]GetUserOrdersProcessingRow, error) {
+	rows, err := q.db.Query(ctx, getUserOrdersProcessing, userLogin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserOrdersProcessingRow
+	for rows.Next() {
+		var i GetUserOrdersProcessingRow
+		if err := rows.Scan(
+			&i.Number,
+			&i.UploadedAt,
+			&i.UserLogin,
+			&i.Status,
+			&i.Accrual,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastCheckedAt,
+			&i.LockedUntil,
+			&i.ParkedAt,
+			&i.ParkedReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserWithdrawals = `-- name: GetUserWithdrawals :many
 SELECT number, processed_at, user_login, sum
 FROM withdrawals
@@ -177,6 +910,103 @@ func (q *Queries) GetUserWithdrawals(ctx context.Context, userLogin string) ([]W
 	return items, nil
 }
 
+const isSessionActive = `-- name: IsSessionActive :one
+SELECT EXISTS (
+    SELECT 1 FROM sessions
+    WHERE id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+)
+`
+
+func (q *Queries) IsSessionActive(ctx context.Context, id string) (bool, error) {
+	row := q.db.QueryRow(ctx, isSessionActive, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const lockActiveUser = `-- name: LockActiveUser :one
+SELECT current
+FROM users
+WHERE login = $1 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+func (q *Queries) LockActiveUser(ctx context.Context, login string) (pgtype.Numeric, error) {
+	row := q.db.QueryRow(ctx, lockActiveUser, login)
+	var current pgtype.Numeric
+	err := row.Scan(&current)
+	return current, err
+}
+
+const lockLoginKey = `-- name: LockLoginKey :exec
+UPDATE login_attempts
+SET locked_until = CURRENT_TIMESTAMP + $1::INTEGER * INTERVAL '1 second'
+WHERE key = $2
+`
+
+type LockLoginKeyParams struct {
+	LockSeconds int32  `json:"lock_seconds"`
+	Key         string `json:"key"`
+}
+
+func (q *Queries) LockLoginKey(ctx context.Context, arg LockLoginKeyParams) error {
+	_, err := q.db.Exec(ctx, lockLoginKey, arg.LockSeconds, arg.Key)
+	return err
+}
+
+const markOutboxEventPublished = `-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET published_at = CURRENT_TIMESTAMP, locked_until = NULL, last_error = NULL
+WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markOutboxEventPublished, id)
+	return err
+}
+
+const parkOrder = `-- name: ParkOrder :exec
+INSERT INTO parked_orders (number, reason)
+VALUES ($1, $2)
+ON CONFLICT (number) DO NOTHING
+`
+
+type ParkOrderParams struct {
+	Number string `json:"number"`
+	Reason string `json:"reason"`
+}
+
+func (q *Queries) ParkOrder(ctx context.Context, arg ParkOrderParams) error {
+	_, err := q.db.Exec(ctx, parkOrder, arg.Number, arg.Reason)
+	return err
+}
+
+const recordLoginAttempt = `-- name: RecordLoginAttempt :one
+INSERT INTO login_attempts (key, failures)
+VALUES ($1, 1)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_attempts.last_failure_at < CURRENT_TIMESTAMP - $2::INTEGER * INTERVAL '1 second'
+        THEN 1
+        ELSE login_attempts.failures + 1
+    END,
+    last_failure_at = CURRENT_TIMESTAMP
+WHERE login_attempts.locked_until IS NULL OR login_attempts.locked_until <= CURRENT_TIMESTAMP
+RETURNING failures
+`
+
+type RecordLoginAttemptParams struct {
+	Key           string `json:"key"`
+	WindowSeconds int32  `json:"window_seconds"`
+}
+
+func (q *Queries) RecordLoginAttempt(ctx context.Context, arg RecordLoginAttemptParams) (int32, error) {
+	row := q.db.QueryRow(ctx, recordLoginAttempt, arg.Key, arg.WindowSeconds)
+	var failures int32
+	err := row.Scan(&failures)
+	return failures, err
+}
+
 const registerUser = `-- name: RegisterUser :exec
 INSERT INTO users (login, password)
 VALUES ($1, $2)
@@ -192,6 +1022,141 @@ func (q *Queries) RegisterUser(ctx context.Context, arg RegisterUserParams) erro
 	return err
 }
 
+const releaseIdempotencyKey = `-- name: ReleaseIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE user_login = $1 AND key = $2
+`
+
+type ReleaseIdempotencyKeyParams struct {
+	UserLogin string `json:"user_login"`
+	Key       string `json:"key"`
+}
+
+func (q *Queries) ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, releaseIdempotencyKey, arg.UserLogin, arg.Key)
+	return err
+}
+
+const releaseLoginAttempt = `-- name: ReleaseLoginAttempt :exec
+UPDATE login_attempts
+SET failures = failures - 1,
+    locked_until = CASE WHEN failures - 1 < $1::INTEGER THEN NULL ELSE locked_until END
+WHERE key = $2 AND failures > 0
+`
+
+type ReleaseLoginAttemptParams struct {
+	MaxAttempts int32  `json:"max_attempts"`
+	Key         string `json:"key"`
+}
+
+func (q *Queries) ReleaseLoginAttempt(ctx context.Context, arg ReleaseLoginAttemptParams) error {
+	_, err := q.db.Exec(ctx, releaseLoginAttempt, arg.MaxAttempts, arg.Key)
+	return err
+}
+
+const rescheduleOrderJob = `-- name: RescheduleOrderJob :exec
+UPDATE order_jobs
+SET next_attempt_at = CURRENT_TIMESTAMP + $1::INTEGER * INTERVAL '1 second',
+    last_checked_at = CURRENT_TIMESTAMP,
+    locked_until = NULL,
+    locked_by = NULL
+WHERE order_number = $2
+`
+
+type RescheduleOrderJobParams struct {
+	DelaySeconds int32  `json:"delay_seconds"`
+	OrderNumber  string `json:"order_number"`
+}
+
+func (q *Queries) RescheduleOrderJob(ctx context.Context, arg RescheduleOrderJobParams) error {
+	_, err := q.db.Exec(ctx, rescheduleOrderJob, arg.DelaySeconds, arg.OrderNumber)
+	return err
+}
+
+const resetLoginAttempts = `-- name: ResetLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE key = $1
+`
+
+func (q *Queries) ResetLoginAttempts(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, resetLoginAttempts, key)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeSession(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, revokeSession, id)
+	return err
+}
+
+const revokeUserSessions = `-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_login = $1 AND id <> $2 AND revoked_at IS NULL
+`
+
+type RevokeUserSessionsParams struct {
+	UserLogin string `json:"user_login"`
+	ID        string `json:"id"`
+}
+
+func (q *Queries) RevokeUserSessions(ctx context.Context, arg RevokeUserSessionsParams) error {
+	_, err := q.db.Exec(ctx, revokeUserSessions, arg.UserLogin, arg.ID)
+	return err
+}
+
+const rotateSession = `-- name: RotateSession :execrows
+UPDATE sessions
+SET previous_refresh_hash = refresh_hash,
+    refresh_hash = $1,
+    expires_at = CURRENT_TIMESTAMP + $2::INTEGER * INTERVAL '1 second'
+WHERE id = $3 AND refresh_hash = $4 AND revoked_at IS NULL
+`
+
+type RotateSessionParams struct {
+	NewRefreshHash []byte `json:"new_refresh_hash"`
+	TtlSeconds     int32  `json:"ttl_seconds"`
+	ID             string `json:"id"`
+	RefreshHash    []byte `json:"refresh_hash"`
+}
+
+func (q *Queries) RotateSession(ctx context.Context, arg RotateSessionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rotateSession,
+		arg.NewRefreshHash,
+		arg.TtlSeconds,
+		arg.ID,
+		arg.RefreshHash,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users
+SET role = $2
+WHERE login = $1 AND deleted_at IS NULL
+`
+
+type SetUserRoleParams struct {
+	Login string `json:"login"`
+	Role  string `json:"role"`
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setUserRole, arg.Login, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateOrderAccrual = `-- name: UpdateOrderAccrual :exec
 UPDATE orders
 SET accrual = $2
@@ -199,8 +1164,8 @@ WHERE number = $1
 `
 
 type UpdateOrderAccrualParams struct {
-	Number  string        `json:"number"`
-	Accrual pgtype.Float4 `json:"accrual"`
+	Number  string         `json:"number"`
+	Accrual pgtype.Numeric `json:"accrual"`
 }
 
 func (q *Queries) UpdateOrderAccrual(ctx context.Context, arg UpdateOrderAccrualParams) error {
@@ -208,20 +1173,24 @@ func (q *Queries) UpdateOrderAccrual(ctx context.Context, arg UpdateOrderAccrual
 	return err
 }
 
-const updateOrderStatus = `-- name: UpdateOrderStatus :exec
+const updateOrderStatus = `-- name: UpdateOrderStatus :execrows
 UPDATE orders
-SET status = $2
-WHERE number = $1
+SET status = $1
+WHERE number = $2 AND status = ANY($3::TEXT[])
 `
 
 type UpdateOrderStatusParams struct {
-	Number string      `json:"number"`
-	Status pgtype.Text `json:"status"`
+	Status       pgtype.Text `json:"status"`
+	Number       string      `json:"number"`
+	FromStatuses []string    `json:"from_statuses"`
 }
 
-func (q *Queries) UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) error {
-	_, err := q.db.Exec(ctx, updateOrderStatus, arg.Number, arg.Status)
-	return err
+func (q *Queries) UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateOrderStatus, arg.Status, arg.Number, arg.FromStatuses)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const updateUserBalance = `-- name: UpdateUserBalance :exec
@@ -231,9 +1200,9 @@ WHERE login = $1
 `
 
 type UpdateUserBalanceParams struct {
-	Login     string        `json:"login"`
-	Current   pgtype.Float4 `json:"current"`
-	Withdrawn pgtype.Float4 `json:"withdrawn"`
+	Login     string         `json:"login"`
+	Current   pgtype.Numeric `json:"current"`
+	Withdrawn pgtype.Numeric `json:"withdrawn"`
 }
 
 func (q *Queries) UpdateUserBalance(ctx context.Context, arg UpdateUserBalanceParams) error {
@@ -241,6 +1210,22 @@ func (q *Queries) UpdateUserBalance(ctx context.Context, arg UpdateUserBalancePa
 	return err
 }
 
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2
+WHERE login = $1
+`
+
+type UpdateUserPasswordParams struct {
+	Login    string `json:"login"`
+	Password []byte `json:"password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.Login, arg.Password)
+	return err
+}
+
 const uploadOrder = `-- name: UploadOrder :exec
 INSERT INTO orders (number, user_login)
 VALUES ($1, $2)
@@ -262,9 +1247,9 @@ VALUES ($1, $2, $3)
 `
 
 type UploadWithdrawalParams struct {
-	Number    string        `json:"number"`
-	UserLogin string        `json:"user_login"`
-	Sum       pgtype.Float4 `json:"sum"`
+	Number    string         `json:"number"`
+	UserLogin string         `json:"user_login"`
+	Sum       pgtype.Numeric `json:"sum"`
 }
 
 func (q *Queries) UploadWithdrawal(ctx context.Context, arg UploadWithdrawalParams) error {
@@ -0,0 +1,75 @@
+-- Baseline is idempotent so databases created by the old createTables can adopt migrations
+
+CREATE TABLE IF NOT EXISTS users (
+    login VARCHAR(50) NOT NULL PRIMARY KEY,
+    password BYTEA NOT NULL,
+    current NUMERIC(12, 2) DEFAULT 0,
+    withdrawn NUMERIC(12, 2) DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+    number VARCHAR(50) NOT NULL PRIMARY KEY,
+    uploaded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, 
+    user_login VARCHAR(50) NOT NULL,
+    status TEXT CHECK (status IN ('NEW', 'PROCESSING', 'INVALID', 'PROCESSED')) DEFAULT 'NEW',
+    accrual NUMERIC(12, 2),
+    FOREIGN KEY (user_login) REFERENCES users(login)
+);
+
+CREATE TABLE IF NOT EXISTS parked_orders (
+    number VARCHAR(50) NOT NULL PRIMARY KEY,
+    parked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reason TEXT NOT NULL,
+    FOREIGN KEY (number) REFERENCES orders(number)
+);
+
+CREATE TABLE IF NOT EXISTS withdrawals (
+    number VARCHAR(50) NOT NULL PRIMARY KEY,
+    processed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, 
+    user_login VARCHAR(50) NOT NULL,
+    sum NUMERIC(12, 2),
+    FOREIGN KEY (user_login) REFERENCES users(login)
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_login VARCHAR(50) NOT NULL,
+    operation TEXT NOT NULL CHECK (operation IN ('OPENING', 'ACCRUAL', 'WITHDRAWAL')),
+    reference VARCHAR(50) NOT NULL,
+    account TEXT NOT NULL CHECK (account IN ('opening', 'accrual', 'current', 'withdrawn')),
+    amount NUMERIC(12, 2) NOT NULL,
+    UNIQUE (operation, reference, account),
+    FOREIGN KEY (user_login) REFERENCES users(login)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_user_login_idx ON ledger_entries (user_login);
+
+-- Balances were stored as REAL before switching to exact NUMERIC
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'users' AND column_name = 'current' AND data_type = 'real'
+    ) THEN
+        ALTER TABLE users
+            ALTER COLUMN current TYPE NUMERIC(12, 2) USING round(current::numeric, 2),
+            ALTER COLUMN withdrawn TYPE NUMERIC(12, 2) USING round(withdrawn::numeric, 2);
+        ALTER TABLE orders
+            ALTER COLUMN accrual TYPE NUMERIC(12, 2) USING round(accrual::numeric, 2);
+        ALTER TABLE withdrawals
+            ALTER COLUMN sum TYPE NUMERIC(12, 2) USING round(sum::numeric, 2);
+    END IF;
+END $$;
+
+-- Open ledger for balances accumulated before ledger was introduced
+INSERT INTO ledger_entries (user_login, operation, reference, account, amount)
+SELECT u.login, 'OPENING', u.login, a.account, a.amount
+FROM users u
+CROSS JOIN LATERAL (VALUES
+    ('current', u.current),
+    ('withdrawn', u.withdrawn),
+    ('opening', -(u.current + u.withdrawn))
+) AS a(account, amount)
+WHERE (u.current <> 0 OR u.withdrawn <> 0)
+  AND NOT EXISTS (SELECT 1 FROM ledger_entries l WHERE l.user_login = u.login);
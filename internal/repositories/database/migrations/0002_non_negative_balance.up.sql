@@ -0,0 +1,3 @@
+-- NOT VALID keeps rows written before the constraint, every new write is checked
+ALTER TABLE users
+    ADD CONSTRAINT users_current_non_negative CHECK (current >= 0) NOT VALID;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(64) NOT NULL PRIMARY KEY,
+    user_login VARCHAR(50) NOT NULL,
+    refresh_hash BYTEA NOT NULL UNIQUE,
+    previous_refresh_hash BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    FOREIGN KEY (user_login) REFERENCES users(login)
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_login_idx ON sessions (user_login);
+CREATE INDEX IF NOT EXISTS sessions_previous_refresh_hash_idx ON sessions (previous_refresh_hash);
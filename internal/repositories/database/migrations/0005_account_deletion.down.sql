@@ -0,0 +1,24 @@
+-- Fails while forfeit entries exist, they have no place in the old schema
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_operation_check,
+    ADD CONSTRAINT ledger_entries_operation_check CHECK (operation IN ('OPENING', 'ACCRUAL', 'WITHDRAWAL')),
+    DROP CONSTRAINT IF EXISTS ledger_entries_account_check,
+    ADD CONSTRAINT ledger_entries_account_check CHECK (account IN ('opening', 'accrual', 'current', 'withdrawn'));
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_user_login_fkey,
+    ADD CONSTRAINT orders_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login);
+ALTER TABLE withdrawals
+    DROP CONSTRAINT IF EXISTS withdrawals_user_login_fkey,
+    ADD CONSTRAINT withdrawals_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login);
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_user_login_fkey,
+    ADD CONSTRAINT ledger_entries_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login);
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT IF EXISTS idempotency_keys_user_login_fkey,
+    ADD CONSTRAINT idempotency_keys_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login);
+ALTER TABLE sessions
+    DROP CONSTRAINT IF EXISTS sessions_user_login_fkey,
+    ADD CONSTRAINT sessions_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login);
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,25 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Deleted account keeps its history under an anonymous login, renaming the user cascades it
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_user_login_fkey,
+    ADD CONSTRAINT orders_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login) ON UPDATE CASCADE;
+ALTER TABLE withdrawals
+    DROP CONSTRAINT IF EXISTS withdrawals_user_login_fkey,
+    ADD CONSTRAINT withdrawals_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login) ON UPDATE CASCADE;
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_user_login_fkey,
+    ADD CONSTRAINT ledger_entries_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login) ON UPDATE CASCADE;
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT IF EXISTS idempotency_keys_user_login_fkey,
+    ADD CONSTRAINT idempotency_keys_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login) ON UPDATE CASCADE;
+ALTER TABLE sessions
+    DROP CONSTRAINT IF EXISTS sessions_user_login_fkey,
+    ADD CONSTRAINT sessions_user_login_fkey FOREIGN KEY (user_login) REFERENCES users(login) ON UPDATE CASCADE;
+
+-- Balance left on a closed account is forfeited
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_operation_check,
+    ADD CONSTRAINT ledger_entries_operation_check CHECK (operation IN ('OPENING', 'ACCRUAL', 'WITHDRAWAL', 'FORFEIT')),
+    DROP CONSTRAINT IF EXISTS ledger_entries_account_check,
+    ADD CONSTRAINT ledger_entries_account_check CHECK (account IN ('opening', 'accrual', 'current', 'withdrawn', 'forfeited'));
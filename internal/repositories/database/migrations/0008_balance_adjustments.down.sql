@@ -0,0 +1,8 @@
+-- Fails while adjustment entries exist, they have no place in the old schema
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_operation_check,
+    ADD CONSTRAINT ledger_entries_operation_check CHECK (operation IN ('OPENING', 'ACCRUAL', 'WITHDRAWAL', 'FORFEIT')),
+    DROP CONSTRAINT IF EXISTS ledger_entries_account_check,
+    ADD CONSTRAINT ledger_entries_account_check CHECK (account IN ('opening', 'accrual', 'current', 'withdrawn', 'forfeited'));
+
+DROP TABLE IF EXISTS balance_adjustments;
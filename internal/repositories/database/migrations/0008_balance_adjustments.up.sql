@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS balance_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_login VARCHAR(50) NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount <> 0),
+    reason TEXT NOT NULL CHECK (reason IN ('GOODWILL', 'ACCRUAL_CORRECTION', 'WITHDRAWAL_CORRECTION', 'OTHER')),
+    note TEXT NOT NULL,
+    operator_login VARCHAR(50) NOT NULL,
+    FOREIGN KEY (user_login) REFERENCES users(login) ON UPDATE CASCADE,
+    FOREIGN KEY (operator_login) REFERENCES users(login) ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS balance_adjustments_user_login_idx ON balance_adjustments (user_login);
+
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_operation_check,
+    ADD CONSTRAINT ledger_entries_operation_check CHECK (operation IN ('OPENING', 'ACCRUAL', 'WITHDRAWAL', 'FORFEIT', 'ADJUSTMENT')),
+    DROP CONSTRAINT IF EXISTS ledger_entries_account_check,
+    ADD CONSTRAINT ledger_entries_account_check CHECK (account IN ('opening', 'accrual', 'current', 'withdrawn', 'forfeited', 'adjustment'));
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS order_jobs (
+    order_number VARCHAR(50) NOT NULL PRIMARY KEY,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    locked_by TEXT,
+    FOREIGN KEY (order_number) REFERENCES orders(number)
+);
+
+CREATE INDEX IF NOT EXISTS order_jobs_next_attempt_at_idx ON order_jobs (next_attempt_at);
+
+INSERT INTO order_jobs (order_number)
+SELECT number
+FROM orders
+WHERE status IN ('NEW', 'PROCESSING')
+  AND number NOT IN (SELECT number FROM parked_orders)
+ON CONFLICT (order_number) DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE order_jobs DROP COLUMN IF EXISTS last_checked_at;
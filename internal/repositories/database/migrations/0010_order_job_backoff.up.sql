@@ -0,0 +1 @@
+ALTER TABLE order_jobs ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP;
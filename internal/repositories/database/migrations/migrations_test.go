@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_second.up.sql":   {Data: []byte("UP 2")},
+		"0002_second.down.sql": {Data: []byte("DOWN 2")},
+		"0001_first.up.sql":    {Data: []byte("UP 1")},
+		"migrations.go":        {Data: []byte("package migrations")},
+	}
+
+	migrations, err := Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, Migration{Version: 1, Name: "first", Up: "UP 1"}, migrations[0])
+	assert.Equal(t, Migration{Version: 2, Name: "second", Up: "UP 2", Down: "DOWN 2"}, migrations[1])
+}
+
+func TestLoadWithoutUp(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"0001_first.down.sql": {Data: []byte("DOWN 1")},
+	})
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load(files)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, int64(i+1), m.Version, "migration versions must be sequential")
+		assert.NotEmpty(t, m.Down, "migration %d_%s has no down script", m.Version, m.Name)
+	}
+}
@@ -3,10 +3,50 @@ INSERT INTO users (login, password)
 VALUES ($1, $2);
 
 -- name: GetUser :one
-SELECT login, password, current, withdrawn
+SELECT login, password, current, withdrawn, role
 FROM users
+WHERE login = $1 AND deleted_at IS NULL;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = $2
+WHERE login = $1 AND deleted_at IS NULL;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2
 WHERE login = $1;
 
+-- name: LockActiveUser :one
+SELECT current
+FROM users
+WHERE login = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: ForfeitUserBalance :exec
+UPDATE users
+SET current = 0
+WHERE login = $1;
+
+-- name: AnonymizeLedgerReferences :exec
+UPDATE ledger_entries
+SET reference = sqlc.arg(anonymous_login)
+WHERE user_login = sqlc.arg(login) AND reference = sqlc.arg(login);
+
+-- name: AnonymizeOutboxEvents :exec
+UPDATE outbox_events
+SET payload = jsonb_set(payload, '{user_login}', to_jsonb(sqlc.arg(anonymous_login)::TEXT))
+WHERE payload->>'user_login' = sqlc.arg(login)::TEXT;
+
+-- name: AnonymizeUser :exec
+UPDATE users
+SET login = sqlc.arg(anonymous_login), password = ''::BYTEA, deleted_at = CURRENT_TIMESTAMP
+WHERE login = sqlc.arg(login);
+
+-- name: DeleteUserIdempotencyKeys :exec
+DELETE FROM idempotency_keys
+WHERE user_login = $1;
+
 -- name: UploadOrder :exec
 INSERT INTO orders (number, user_login)
 VALUES ($1, $2);
@@ -32,10 +72,15 @@ UPDATE users
 SET current = current + $2, withdrawn = withdrawn + $3
 WHERE login = $1;
 
--- name: UpdateOrderStatus :exec
+-- name: DebitUserBalance :execrows
+UPDATE users
+SET current = current - sqlc.arg(amount)::NUMERIC, withdrawn = withdrawn + sqlc.arg(amount)::NUMERIC
+WHERE login = sqlc.arg(login) AND current >= sqlc.arg(amount)::NUMERIC;
+
+-- name: UpdateOrderStatus :execrows
 UPDATE orders
-SET status = $2
-WHERE number = $1;
+SET status = sqlc.arg(status)
+WHERE number = sqlc.arg(number) AND status = ANY(sqlc.arg(from_statuses)::TEXT[]);
 
 -- name: UpdateOrderAccrual :exec
 UPDATE orders
@@ -47,12 +92,253 @@ SELECT number, uploaded_at, user_login, status, accrual
 FROM orders
 WHERE status = $1;
 
--- name: GetUnprocessedOrders :many
-SELECT number, uploaded_at, user_login, status, accrual
-FROM orders
-WHERE status in ('NEW', 'PROCESSING');
+-- name: ParkOrder :exec
+INSERT INTO parked_orders (number, reason)
+VALUES ($1, $2)
+ON CONFLICT (number) DO NOTHING;
 
 -- name: GetOrderByNumber :one
 SELECT number, uploaded_at, user_login, status, accrual
 FROM orders
 WHERE number = $1;
+
+-- name: AddLedgerEntry :exec
+INSERT INTO ledger_entries (user_login, operation, reference, account, amount)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetLedgerBalance :one
+SELECT
+    COALESCE(SUM(amount) FILTER (WHERE account = 'current'), 0)::NUMERIC(12, 2) AS current,
+    COALESCE(SUM(amount) FILTER (WHERE account = 'withdrawn'), 0)::NUMERIC(12, 2) AS withdrawn
+FROM ledger_entries
+WHERE user_login = $1;
+
+-- name: GetUserLedger :many
+SELECT id, created_at, user_login, operation, reference, account, amount
+FROM ledger_entries
+WHERE user_login = $1
+ORDER BY id;
+
+-- name: DeleteExpiredIdempotencyKeys :exec
+DELETE FROM idempotency_keys
+WHERE user_login = sqlc.arg(user_login)
+  AND created_at < CURRENT_TIMESTAMP - sqlc.arg(window_seconds)::INTEGER * INTERVAL '1 second';
+
+-- name: ClaimIdempotencyKey :execrows
+INSERT INTO idempotency_keys (user_login, key, request_hash, locked_until)
+VALUES (
+    sqlc.arg(user_login),
+    sqlc.arg(key),
+    sqlc.arg(request_hash),
+    CURRENT_TIMESTAMP + sqlc.arg(lease_seconds)::INTEGER * INTERVAL '1 second'
+)
+ON CONFLICT (user_login, key) DO UPDATE
+SET locked_until = EXCLUDED.locked_until, created_at = CURRENT_TIMESTAMP
+WHERE idempotency_keys.status_code IS NULL
+  AND idempotency_keys.request_hash = EXCLUDED.request_hash
+  AND (idempotency_keys.locked_until IS NULL OR idempotency_keys.locked_until < CURRENT_TIMESTAMP);
+
+-- name: GetIdempotencyKey :one
+SELECT user_login, key, request_hash, status_code, content_type, response_body, created_at, locked_until
+FROM idempotency_keys
+WHERE user_login = $1 AND key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status_code = $3, content_type = $4, response_body = $5
+WHERE user_login = $1 AND key = $2;
+
+-- name: ReleaseIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE user_login = $1 AND key = $2;
+
+-- name: CreateSession :exec
+INSERT INTO sessions (id, user_login, refresh_hash, expires_at)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(user_login),
+    sqlc.arg(refresh_hash),
+    CURRENT_TIMESTAMP + sqlc.arg(ttl_seconds)::INTEGER * INTERVAL '1 second'
+);
+
+-- name: GetSessionByRefreshHash :one
+SELECT id, user_login, refresh_hash,
+    revoked_at IS NOT NULL AS revoked,
+    expires_at <= CURRENT_TIMESTAMP AS expired
+FROM sessions
+WHERE refresh_hash = $1 OR previous_refresh_hash = $1;
+
+-- name: RotateSession :execrows
+UPDATE sessions
+SET previous_refresh_hash = refresh_hash,
+    refresh_hash = sqlc.arg(new_refresh_hash),
+    expires_at = CURRENT_TIMESTAMP + sqlc.arg(ttl_seconds)::INTEGER * INTERVAL '1 second'
+WHERE id = sqlc.arg(id) AND refresh_hash = sqlc.arg(refresh_hash) AND revoked_at IS NULL;
+
+-- name: RevokeSession :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_login = $1 AND id <> $2 AND revoked_at IS NULL;
+
+-- name: IsSessionActive :one
+SELECT EXISTS (
+    SELECT 1 FROM sessions
+    WHERE id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+);
+
+-- name: DeleteStaleSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= CURRENT_TIMESTAMP OR revoked_at IS NOT NULL;
+
+-- name: DeleteStaleLoginAttempts :execrows
+DELETE FROM login_attempts
+WHERE last_failure_at < CURRENT_TIMESTAMP - sqlc.arg(window_seconds)::INTEGER * INTERVAL '1 second'
+  AND (locked_until IS NULL OR locked_until < CURRENT_TIMESTAMP);
+
+-- name: RecordLoginAttempt :one
+INSERT INTO login_attempts (key, failures)
+VALUES (sqlc.arg(key), 1)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_attempts.last_failure_at < CURRENT_TIMESTAMP - sqlc.arg(window_seconds)::INTEGER * INTERVAL '1 second'
+        THEN 1
+        ELSE login_attempts.failures + 1
+    END,
+    last_failure_at = CURRENT_TIMESTAMP
+WHERE login_attempts.locked_until IS NULL OR login_attempts.locked_until <= CURRENT_TIMESTAMP
+RETURNING failures;
+
+-- name: ReleaseLoginAttempt :exec
+UPDATE login_attempts
+SET failures = failures - 1,
+    locked_until = CASE WHEN failures - 1 < sqlc.arg(max_attempts)::INTEGER THEN NULL ELSE locked_until END
+WHERE key = sqlc.arg(key) AND failures > 0;
+
+-- name: LockLoginKey :exec
+UPDATE login_attempts
+SET locked_until = CURRENT_TIMESTAMP + sqlc.arg(lock_seconds)::INTEGER * INTERVAL '1 second'
+WHERE key = sqlc.arg(key);
+
+-- name: GetLoginLock :one
+SELECT GREATEST(CEIL(EXTRACT(EPOCH FROM locked_until - CURRENT_TIMESTAMP)), 0)::INTEGER AS locked_seconds
+FROM login_attempts
+WHERE key = $1 AND locked_until IS NOT NULL;
+
+-- name: ResetLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE key = $1;
+
+-- name: CreateBalanceAdjustment :one
+INSERT INTO balance_adjustments (user_login, amount, reason, note, operator_login)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at, user_login, amount, reason, note, operator_login;
+
+-- name: GetUserAdjustments :many
+SELECT id, created_at, user_login, amount, reason, note, operator_login
+FROM balance_adjustments
+WHERE user_login = $1
+ORDER BY created_at DESC;
+
+-- name: EnqueueOrderJob :exec
+INSERT INTO order_jobs (order_number)
+VALUES ($1)
+ON CONFLICT (order_number) DO NOTHING;
+
+-- name: ClaimOrderJobs :many
+WITH due AS (
+    SELECT order_number
+    FROM order_jobs
+    WHERE next_attempt_at <= CURRENT_TIMESTAMP
+      AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(batch_size)::INTEGER
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE order_jobs j
+SET attempts = j.attempts + 1,
+    locked_until = CURRENT_TIMESTAMP + sqlc.arg(lease_seconds)::INTEGER * INTERVAL '1 second',
+    locked_by = sqlc.arg(worker)::TEXT
+FROM due, orders o
+WHERE j.order_number = due.order_number AND o.number = j.order_number
+RETURNING o.number, o.uploaded_at, o.user_login, o.status, o.accrual, j.attempts,
+    EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - o.uploaded_at)::INTEGER AS age_seconds;
+
+-- name: ExtendOrderJobLeases :exec
+UPDATE order_jobs
+SET locked_until = CURRENT_TIMESTAMP + sqlc.arg(lease_seconds)::INTEGER * INTERVAL '1 second'
+WHERE locked_by = sqlc.arg(worker)::TEXT;
+
+-- name: RescheduleOrderJob :exec
+UPDATE order_jobs
+SET next_attempt_at = CURRENT_TIMESTAMP + sqlc.arg(delay_seconds)::INTEGER * INTERVAL '1 second',
+    last_checked_at = CURRENT_TIMESTAMP,
+    locked_until = NULL,
+    locked_by = NULL
+WHERE order_number = sqlc.arg(order_number);
+
+-- name: DeleteOrderJob :exec
+DELETE FROM order_jobs
+WHERE order_number = $1;
+
+-- name: GetUserOrdersProcessing :many
+SELECT o.number, o.uploaded_at, o.user_login, o.status, o.accrual,
+    j.attempts, j.next_attempt_at, j.last_checked_at, j.locked_until,
+    p.parked_at, p.reason AS parked_reason
+FROM orders o
+LEFT JOIN order_jobs j ON j.order_number = o.number
+LEFT JOIN parked_orders p ON p.number = o.number
+WHERE o.user_login = $1
+ORDER BY o.uploaded_at DESC;
+
+-- name: GetParkedOrders :many
+SELECT o.number, o.uploaded_at, o.user_login, o.status, o.accrual,
+    j.attempts, j.next_attempt_at, j.last_checked_at, j.locked_until,
+    p.parked_at, p.reason AS parked_reason
+FROM orders o
+LEFT JOIN order_jobs j ON j.order_number = o.number
+LEFT JOIN parked_orders p ON p.number = o.number
+WHERE p.number IS NOT NULL
+ORDER BY p.parked_at DESC;
+
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events (event_type, aggregate_id, payload)
+VALUES ($1, $2, $3);
+
+-- name: ClaimOutboxEvents :many
+WITH due AS (
+    SELECT id
+    FROM outbox_events
+    WHERE published_at IS NULL
+      AND next_attempt_at <= CURRENT_TIMESTAMP
+      AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+    ORDER BY id
+    LIMIT sqlc.arg(batch_size)::INTEGER
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE outbox_events e
+SET attempts = e.attempts + 1,
+    locked_until = CURRENT_TIMESTAMP + sqlc.arg(lease_seconds)::INTEGER * INTERVAL '1 second'
+FROM due
+WHERE e.id = due.id
+RETURNING e.id, e.created_at, e.event_type, e.aggregate_id, e.payload, e.attempts;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET published_at = CURRENT_TIMESTAMP, locked_until = NULL, last_error = NULL
+WHERE id = $1;
+
+-- name: FailOutboxEvent :exec
+UPDATE outbox_events
+SET next_attempt_at = CURRENT_TIMESTAMP + sqlc.arg(delay_seconds)::INTEGER * INTERVAL '1 second',
+    locked_until = NULL,
+    last_error = sqlc.arg(last_error)
+WHERE id = sqlc.arg(id);
+
+-- name: DeletePublishedOutboxEvents :exec
+DELETE FROM outbox_events
+WHERE published_at < CURRENT_TIMESTAMP - sqlc.arg(retention_seconds)::INTEGER * INTERVAL '1 second';
@@ -2,8 +2,6 @@ package repositories
 
 import (
 	"context"
-	"os"
-	"path/filepath"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -11,6 +9,7 @@ import (
 	"github.com/morzisorn/gofermart/internal/logger"
 	"github.com/morzisorn/gofermart/internal/repositories/database"
 	gen "github.com/morzisorn/gofermart/internal/repositories/database/generated"
+	"github.com/morzisorn/gofermart/internal/repositories/database/migrations"
 )
 
 func NewRepository(cfg *config.Config) Repository {
@@ -19,7 +18,7 @@ func NewRepository(cfg *config.Config) Repository {
 		logger.Log.Panic(err.Error())
 	}
 
-	err = createTables(db)
+	err = migrate(db)
 	if err != nil {
 		logger.Log.Panic(err.Error())
 	}
@@ -27,27 +26,26 @@ func NewRepository(cfg *config.Config) Repository {
 	q := gen.New(db)
 
 	return &DBRepository{
-		users:  database.NewUserRepository(q),
-		orders: database.NewOrderRepository(q, db),
+		db: db,
+
+		users:  database.NewUserRepository(q, db),
+		orders: database.NewOrderRepository(q, db, cfg.OutboxSink != config.OutboxSinkNone),
+		ledger: database.NewLedgerRepository(q),
+		jobs:   database.NewOrderJobRepository(q),
+
+		idempotency: database.NewIdempotencyRepository(q),
+		sessions:    database.NewSessionRepository(q),
+		logins:      database.NewLoginAttemptRepository(q, db),
+		adjustments: database.NewAdjustmentRepository(q, db),
+		outbox:      database.NewOutboxRepository(q),
 	}
 }
 
-func createTables(db *pgxpool.Pool) error {
-	rootDir, err := config.GetProjectRoot()
+func migrate(db *pgxpool.Pool) error {
+	m, err := migrations.NewMigrator(db)
 	if err != nil {
 		return err
 	}
-	filepath := filepath.Join(rootDir, "internal", "repositories", "database", "schema", "schema.sql")
 
-	script, err := os.ReadFile(filepath)
-	if err != nil {
-		return err
-	}
-
-	_, err = db.Exec(context.Background(), string(script))
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return m.Up(context.Background())
 }
@@ -3,6 +3,8 @@ package repositories
 import (
 	"context"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+
 	"github.com/morzisorn/gofermart/internal/models"
 	"github.com/morzisorn/gofermart/internal/repositories/database"
 )
@@ -10,21 +12,73 @@ import (
 type Repository interface {
 	RegisterUser(ctx context.Context, user *models.User) error
 	GetUser(ctx context.Context, login string) (*models.User, error)
+	UpdateUserPassword(ctx context.Context, login string, password []byte) error
+	DeleteUser(ctx context.Context, login, anonymousLogin string, forfeit bool) error
+	SetUserRole(ctx context.Context, login, role string) error
 
 	UploadOrder(ctx context.Context, login, number string) (string, error)
 	UpdateOrderStatus(ctx context.Context, number, status string) error
-	Withdraw(ctx context.Context, login, number string, sum float64) error
+	Withdraw(ctx context.Context, login, number string, sum models.Money) error
 	GetUserOrders(ctx context.Context, login string) (*[]models.Order, error)
 	GetUserWithdrawals(ctx context.Context, login string) (*[]models.Withdrawal, error)
 	GetOrdersWithStatus(ctx context.Context, status string) (*[]models.Order, error)
-	OrderProcessed(ctx context.Context, login, number string, accrual float64) error
-	GetUpprocessedOrders(ctx context.Context) (*[]models.Order, error)
+	OrderProcessed(ctx context.Context, login, number string, accrual models.Money) error
 	GetOrderByNumber(ctx context.Context, number string) (*models.Order, error)
+	ParkOrder(ctx context.Context, number, reason string) error
+
+	ClaimOrderJobs(ctx context.Context, worker string, batchSize, leaseSeconds int) (*[]models.OrderJob, error)
+	RescheduleOrderJob(ctx context.Context, number string, delaySeconds int) error
+	ExtendOrderJobLeases(ctx context.Context, worker string, leaseSeconds int) error
+	CompleteOrderJob(ctx context.Context, number string) error
+	GetUserOrdersProcessing(ctx context.Context, login string) (*[]models.OrderProcessing, error)
+	GetParkedOrders(ctx context.Context) (*[]models.OrderProcessing, error)
+
+	ClaimOutboxEvents(ctx context.Context, batchSize, leaseSeconds int) (*[]models.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	FailOutboxEvent(ctx context.Context, id int64, delaySeconds int, reason string) error
+	DeletePublishedOutboxEvents(ctx context.Context, retentionSeconds int) error
+
+	GetLedgerBalance(ctx context.Context, login string) (*models.UserBalance, error)
+	GetUserLedger(ctx context.Context, login string) (*[]models.LedgerEntry, error)
+
+	ClaimIdempotencyKey(ctx context.Context, login, key string, requestHash []byte, windowSeconds, leaseSeconds int) (bool, error)
+	GetIdempotencyKey(ctx context.Context, login, key string) (*models.IdempotentResponse, error)
+	CompleteIdempotencyKey(ctx context.Context, login, key string, resp *models.IdempotentResponse) error
+	ReleaseIdempotencyKey(ctx context.Context, login, key string) error
+
+	CreateSession(ctx context.Context, id, login string, refreshHash []byte, ttlSeconds int) error
+	GetSessionByRefreshHash(ctx context.Context, refreshHash []byte) (*models.Session, error)
+	RotateSession(ctx context.Context, id string, refreshHash, newRefreshHash []byte, ttlSeconds int) (bool, error)
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, login, exceptID string) error
+	IsSessionActive(ctx context.Context, id string) (bool, error)
+	DeleteStaleSessions(ctx context.Context) (int64, error)
+
+	AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.BalanceAdjustment, error)
+	GetUserAdjustments(ctx context.Context, login string) (*[]models.BalanceAdjustment, error)
+
+	RecordLoginAttempt(ctx context.Context, key string, windowSeconds int, lockout func(attempts int) int) (int, error)
+	ReleaseLoginAttempt(ctx context.Context, key string, limit int) error
+	GetLoginLock(ctx context.Context, key string) (int, error)
+	ResetLoginAttempts(ctx context.Context, key string) error
+	DeleteStaleLoginAttempts(ctx context.Context, windowSeconds int) (int64, error)
+
+	Close()
 }
 
 type DBRepository struct {
+	db *pgxpool.Pool
+
 	users  database.UserRepository
 	orders database.OrderRepository
+	ledger database.LedgerRepository
+	jobs   database.OrderJobRepository
+
+	idempotency database.IdempotencyRepository
+	sessions    database.SessionRepository
+	logins      database.LoginAttemptRepository
+	adjustments database.AdjustmentRepository
+	outbox      database.OutboxRepository
 }
 
 func (r *DBRepository) RegisterUser(ctx context.Context, user *models.User) error {
@@ -35,6 +89,18 @@ func (r *DBRepository) GetUser(ctx context.Context, login string) (*models.User,
 	return r.users.GetUser(ctx, login)
 }
 
+func (r *DBRepository) UpdateUserPassword(ctx context.Context, login string, password []byte) error {
+	return r.users.UpdateUserPassword(ctx, login, password)
+}
+
+func (r *DBRepository) SetUserRole(ctx context.Context, login, role string) error {
+	return r.users.SetUserRole(ctx, login, role)
+}
+
+func (r *DBRepository) DeleteUser(ctx context.Context, login, anonymousLogin string, forfeit bool) error {
+	return r.users.DeleteUser(ctx, login, anonymousLogin, forfeit)
+}
+
 func (r *DBRepository) UploadOrder(ctx context.Context, login, number string) (string, error) {
 	return r.orders.UploadOrder(ctx, login, number)
 }
@@ -47,7 +113,7 @@ func (r *DBRepository) GetOrdersWithStatus(ctx context.Context, status string) (
 	return r.orders.GetOrdersWithStatus(ctx, status)
 }
 
-func (r *DBRepository) Withdraw(ctx context.Context, login, number string, sum float64) error {
+func (r *DBRepository) Withdraw(ctx context.Context, login, number string, sum models.Money) error {
 	return r.orders.Withdraw(ctx, login, number, sum)
 }
 
@@ -59,13 +125,137 @@ func (r *DBRepository) GetUserWithdrawals(ctx context.Context, login string) (*[
 	return r.orders.GetUserWithdrawals(ctx, login)
 }
 
-func (r *DBRepository) OrderProcessed(ctx context.Context, login, number string, accrual float64) error {
+func (r *DBRepository) OrderProcessed(ctx context.Context, login, number string, accrual models.Money) error {
 	return r.orders.OrderProcessed(ctx, login, number, accrual)
 }
-func (r *DBRepository) GetUpprocessedOrders(ctx context.Context) (*[]models.Order, error) {
-	return r.orders.GetUpprocessedOrders(ctx)
-}
-
 func (r *DBRepository) GetOrderByNumber(ctx context.Context, number string) (*models.Order, error) {
 	return r.orders.GetOrderByNumber(ctx, number)
-}
\ No newline at end of file
+}
+
+func (r *DBRepository) ParkOrder(ctx context.Context, number, reason string) error {
+	return r.orders.ParkOrder(ctx, number, reason)
+}
+
+func (r *DBRepository) ClaimOrderJobs(ctx context.Context, worker string, batchSize, leaseSeconds int) (*[]models.OrderJob, error) {
+	return r.jobs.ClaimOrderJobs(ctx, worker, batchSize, leaseSeconds)
+}
+
+func (r *DBRepository) RescheduleOrderJob(ctx context.Context, number string, delaySeconds int) error {
+	return r.jobs.RescheduleOrderJob(ctx, number, delaySeconds)
+}
+
+func (r *DBRepository) ExtendOrderJobLeases(ctx context.Context, worker string, leaseSeconds int) error {
+	return r.jobs.ExtendOrderJobLeases(ctx, worker, leaseSeconds)
+}
+
+func (r *DBRepository) CompleteOrderJob(ctx context.Context, number string) error {
+	return r.jobs.CompleteOrderJob(ctx, number)
+}
+
+func (r *DBRepository) GetUserOrdersProcessing(ctx context.Context, login string) (*[]models.OrderProcessing, error) {
+	return r.jobs.GetUserOrdersProcessing(ctx, login)
+}
+
+func (r *DBRepository) GetParkedOrders(ctx context.Context) (*[]models.OrderProcessing, error) {
+	return r.jobs.GetParkedOrders(ctx)
+}
+
+func (r *DBRepository) ClaimOutboxEvents(ctx context.Context, batchSize, leaseSeconds int) (*[]models.OutboxEvent, error) {
+	return r.outbox.ClaimOutboxEvents(ctx, batchSize, leaseSeconds)
+}
+
+func (r *DBRepository) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	return r.outbox.MarkOutboxEventPublished(ctx, id)
+}
+
+func (r *DBRepository) FailOutboxEvent(ctx context.Context, id int64, delaySeconds int, reason string) error {
+	return r.outbox.FailOutboxEvent(ctx, id, delaySeconds, reason)
+}
+
+func (r *DBRepository) DeletePublishedOutboxEvents(ctx context.Context, retentionSeconds int) error {
+	return r.outbox.DeletePublishedOutboxEvents(ctx, retentionSeconds)
+}
+
+func (r *DBRepository) GetLedgerBalance(ctx context.Context, login string) (*models.UserBalance, error) {
+	return r.ledger.GetLedgerBalance(ctx, login)
+}
+
+func (r *DBRepository) GetUserLedger(ctx context.Context, login string) (*[]models.LedgerEntry, error) {
+	return r.ledger.GetUserLedger(ctx, login)
+}
+
+func (r *DBRepository) ClaimIdempotencyKey(ctx context.Context, login, key string, requestHash []byte, windowSeconds, leaseSeconds int) (bool, error) {
+	return r.idempotency.ClaimIdempotencyKey(ctx, login, key, requestHash, windowSeconds, leaseSeconds)
+}
+
+func (r *DBRepository) GetIdempotencyKey(ctx context.Context, login, key string) (*models.IdempotentResponse, error) {
+	return r.idempotency.GetIdempotencyKey(ctx, login, key)
+}
+
+func (r *DBRepository) CompleteIdempotencyKey(ctx context.Context, login, key string, resp *models.IdempotentResponse) error {
+	return r.idempotency.CompleteIdempotencyKey(ctx, login, key, resp)
+}
+
+func (r *DBRepository) ReleaseIdempotencyKey(ctx context.Context, login, key string) error {
+	return r.idempotency.ReleaseIdempotencyKey(ctx, login, key)
+}
+
+func (r *DBRepository) CreateSession(ctx context.Context, id, login string, refreshHash []byte, ttlSeconds int) error {
+	return r.sessions.CreateSession(ctx, id, login, refreshHash, ttlSeconds)
+}
+
+func (r *DBRepository) GetSessionByRefreshHash(ctx context.Context, refreshHash []byte) (*models.Session, error) {
+	return r.sessions.GetSessionByRefreshHash(ctx, refreshHash)
+}
+
+func (r *DBRepository) RotateSession(ctx context.Context, id string, refreshHash, newRefreshHash []byte, ttlSeconds int) (bool, error) {
+	return r.sessions.RotateSession(ctx, id, refreshHash, newRefreshHash, ttlSeconds)
+}
+
+func (r *DBRepository) RevokeSession(ctx context.Context, id string) error {
+	return r.sessions.RevokeSession(ctx, id)
+}
+
+func (r *DBRepository) RevokeUserSessions(ctx context.Context, login, exceptID string) error {
+	return r.sessions.RevokeUserSessions(ctx, login, exceptID)
+}
+
+func (r *DBRepository) IsSessionActive(ctx context.Context, id string) (bool, error) {
+	return r.sessions.IsSessionActive(ctx, id)
+}
+
+func (r *DBRepository) DeleteStaleSessions(ctx context.Context) (int64, error) {
+	return r.sessions.DeleteStaleSessions(ctx)
+}
+
+func (r *DBRepository) AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.BalanceAdjustment, error) {
+	return r.adjustments.AdjustBalance(ctx, adj)
+}
+
+func (r *DBRepository) GetUserAdjustments(ctx context.Context, login string) (*[]models.BalanceAdjustment, error) {
+	return r.adjustments.GetUserAdjustments(ctx, login)
+}
+
+func (r *DBRepository) RecordLoginAttempt(ctx context.Context, key string, windowSeconds int, lockout func(attempts int) int) (int, error) {
+	return r.logins.RecordLoginAttempt(ctx, key, windowSeconds, lockout)
+}
+
+func (r *DBRepository) ReleaseLoginAttempt(ctx context.Context, key string, limit int) error {
+	return r.logins.ReleaseLoginAttempt(ctx, key, limit)
+}
+
+func (r *DBRepository) GetLoginLock(ctx context.Context, key string) (int, error) {
+	return r.logins.GetLoginLock(ctx, key)
+}
+
+func (r *DBRepository) ResetLoginAttempts(ctx context.Context, key string) error {
+	return r.logins.ResetLoginAttempts(ctx, key)
+}
+
+func (r *DBRepository) DeleteStaleLoginAttempts(ctx context.Context, windowSeconds int) (int64, error) {
+	return r.logins.DeleteStaleLoginAttempts(ctx, windowSeconds)
+}
+
+func (r *DBRepository) Close() {
+	r.db.Close()
+}
@@ -0,0 +1,75 @@
+package adjustments
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/logger"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/morzisorn/gofermart/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// AdjustmentService applies manual balance corrections made by operators.
+// Every adjustment keeps reason, note and operator, and is visible in user history.
+type AdjustmentService struct {
+	repo repositories.Repository
+}
+
+func NewAdjustmentService(repo repositories.Repository) *AdjustmentService {
+	return &AdjustmentService{repo: repo}
+}
+
+// Adjust credits positive or debits negative amount to user balance
+func (s *AdjustmentService) Adjust(ctx context.Context, operator, login string, req *models.BalanceAdjustment) (*models.BalanceAdjustment, error) {
+	if req.Amount == 0 {
+		return nil, fmt.Errorf("adjust balance error: %w", errs.ErrInvalidAdjustment)
+	}
+
+	switch req.Reason {
+	case models.AdjustmentReasonGOODWILL, models.AdjustmentReasonACCRUALCORRECTION,
+		models.AdjustmentReasonWITHDRAWALCORRECTION, models.AdjustmentReasonOTHER:
+	default:
+		return nil, fmt.Errorf("adjust balance error: %w", errs.ErrInvalidAdjustment)
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if note == "" {
+		return nil, fmt.Errorf("adjust balance error: %w", errs.ErrInvalidAdjustment)
+	}
+
+	adj, err := s.repo.AdjustBalance(ctx, &models.BalanceAdjustment{
+		UserLogin:     login,
+		Amount:        req.Amount,
+		Reason:        req.Reason,
+		Note:          note,
+		OperatorLogin: operator,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("adjust balance error: %w", err)
+	}
+
+	logger.Log.Info("Balance adjusted",
+		zap.String("audit", "balance_adjustment"),
+		zap.Int64("id", adj.ID),
+		zap.String("login", login),
+		zap.String("operator", operator),
+		zap.String("amount", adj.Amount.String()),
+		zap.String("reason", adj.Reason),
+	)
+
+	return adj, nil
+}
+
+func (s *AdjustmentService) GetUserAdjustments(ctx context.Context, login string) (*[]models.BalanceAdjustment, error) {
+	adjs, err := s.repo.GetUserAdjustments(ctx, login)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("get user adjustments error: %w", err)
+	case len(*adjs) == 0:
+		return nil, fmt.Errorf("get user adjustments error: %w", errs.ErrNoData)
+	}
+	return adjs, nil
+}
@@ -0,0 +1,31 @@
+package adjustments
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdjustRejectsIncompleteRequest(t *testing.T) {
+	s := NewAdjustmentService(nil)
+
+	tests := []struct {
+		name string
+		req  models.BalanceAdjustment
+	}{
+		{name: "zero amount", req: models.BalanceAdjustment{Reason: models.AdjustmentReasonGOODWILL, Note: "note"}},
+		{name: "unknown reason", req: models.BalanceAdjustment{Amount: models.NewMoney(10, 0), Reason: "GIFT", Note: "note"}},
+		{name: "blank note", req: models.BalanceAdjustment{Amount: models.NewMoney(-10, 0), Reason: models.AdjustmentReasonOTHER, Note: "  "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Adjust(context.Background(), "admin", "user", &tt.req)
+			assert.True(t, errors.Is(err, errs.ErrInvalidAdjustment), err)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/models"
+)
+
+// Store keeps claimed keys and their responses
+type Store interface {
+	ClaimIdempotencyKey(ctx context.Context, login, key string, requestHash []byte, windowSeconds, leaseSeconds int) (bool, error)
+	GetIdempotencyKey(ctx context.Context, login, key string) (*models.IdempotentResponse, error)
+	CompleteIdempotencyKey(ctx context.Context, login, key string, resp *models.IdempotentResponse) error
+	ReleaseIdempotencyKey(ctx context.Context, login, key string) error
+}
+
+// IdempotencyService remembers responses by user and Idempotency-Key so a retried
+// request gets the original result instead of being executed again.
+type IdempotencyService struct {
+	repo   Store
+	window int
+	lease  int
+}
+
+func NewIdempotencyService(repo Store, cnfg *config.Config) *IdempotencyService {
+	return &IdempotencyService{
+		repo:   repo,
+		window: cnfg.IdempotencyWindow,
+		lease:  cnfg.IdempotencyLease,
+	}
+}
+
+// Begin returns nil response when key is claimed and request must be executed,
+// or the stored response when it is a retry of a finished request.
+func (s *IdempotencyService) Begin(ctx context.Context, login, key string, requestHash []byte) (*models.IdempotentResponse, error) {
+	claimed, err := s.repo.ClaimIdempotencyKey(ctx, login, key, requestHash, s.window, s.lease)
+	if err != nil {
+		return nil, fmt.Errorf("begin idempotent request error: %w", err)
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	resp, err := s.repo.GetIdempotencyKey(ctx, login, key)
+	if err != nil {
+		return nil, fmt.Errorf("begin idempotent request error: %w", err)
+	}
+
+	switch {
+	case !bytes.Equal(resp.RequestHash, requestHash):
+		return nil, fmt.Errorf("begin idempotent request error: %w", errs.ErrIdempotencyKeyReused)
+	case !resp.Completed:
+		return nil, fmt.Errorf("begin idempotent request error: %w", errs.ErrIdempotencyKeyInProgress)
+	}
+
+	return resp, nil
+}
+
+func (s *IdempotencyService) Complete(ctx context.Context, login, key string, resp *models.IdempotentResponse) error {
+	if err := s.repo.CompleteIdempotencyKey(ctx, login, key, resp); err != nil {
+		return fmt.Errorf("complete idempotent request error: %w", err)
+	}
+	return nil
+}
+
+// Release frees key after failure so the client can retry the request
+func (s *IdempotencyService) Release(ctx context.Context, login, key string) error {
+	if err := s.repo.ReleaseIdempotencyKey(ctx, login, key); err != nil {
+		return fmt.Errorf("release idempotent request error: %w", err)
+	}
+	return nil
+}
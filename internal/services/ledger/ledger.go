@@ -0,0 +1,35 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/morzisorn/gofermart/internal/repositories"
+)
+
+// LedgerService reads immutable balance history. Entries are written by the repositories
+// in the same transaction as the balance change they explain.
+type LedgerService struct {
+	repo repositories.Repository
+}
+
+func NewLedgerService(repo repositories.Repository) *LedgerService {
+	return &LedgerService{repo: repo}
+}
+
+func (ls *LedgerService) GetBalance(ctx context.Context, login string) (*models.UserBalance, error) {
+	balance, err := ls.repo.GetLedgerBalance(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("get ledger balance error: %w", err)
+	}
+	return balance, nil
+}
+
+func (ls *LedgerService) GetUserEntries(ctx context.Context, login string) (*[]models.LedgerEntry, error) {
+	entries, err := ls.repo.GetUserLedger(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("get user ledger error: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,180 @@
+package loginguard
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Store keeps login attempt counters. Repository implements it on PostgreSQL,
+// MemoryStore is for single instance deployments.
+type Store interface {
+	RecordLoginAttempt(ctx context.Context, key string, windowSeconds int, lockout func(attempts int) int) (int, error)
+	ReleaseLoginAttempt(ctx context.Context, key string, limit int) error
+	GetLoginLock(ctx context.Context, key string) (int, error)
+	ResetLoginAttempts(ctx context.Context, key string) error
+	DeleteStaleLoginAttempts(ctx context.Context, windowSeconds int) (int64, error)
+}
+
+// LockedError is returned while login or client address is locked out
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter)
+}
+
+func (e *LockedError) Unwrap() error {
+	return errs.ErrTooManyLoginAttempts
+}
+
+// RetryAfterSeconds formats lockout for Retry-After header, rounding up
+func (e *LockedError) RetryAfterSeconds() string {
+	return strconv.Itoa(int((e.RetryAfter + time.Second - 1) / time.Second))
+}
+
+// LoginGuardService counts login attempts per account and per client IP. Attempt is counted
+// before password is verified and uncounted on success, so parallel guesses can not all pass
+// below the limit. Key which reaches its limit is locked, every next attempt doubles the lockout.
+type LoginGuardService struct {
+	store       Store
+	maxLogin    int
+	maxIP       int
+	lockoutBase int
+	lockoutMax  int
+	window      int
+}
+
+type guardKey struct {
+	key   string
+	limit int
+}
+
+func NewLoginGuardService(store Store, cnfg *config.Config) *LoginGuardService {
+	return &LoginGuardService{
+		store:       store,
+		maxLogin:    cnfg.LoginMaxAttempts,
+		maxIP:       cnfg.LoginIPMaxAttempts,
+		lockoutBase: cnfg.LoginLockoutBase,
+		lockoutMax:  cnfg.LoginLockoutMax,
+		window:      cnfg.LoginFailureWindow,
+	}
+}
+
+// Attempt counts login attempt of login and client IP. It returns LockedError without
+// counting if either is locked out.
+func (s *LoginGuardService) Attempt(ctx context.Context, login, ip string) error {
+	keys := s.keys(login, ip)
+	for i, k := range keys {
+		attempts, err := s.store.RecordLoginAttempt(ctx, k.key, s.window, func(attempts int) int {
+			return s.lockout(attempts, k.limit)
+		})
+		if err != nil {
+			s.release(ctx, keys[:i])
+			return fmt.Errorf("record login attempt error: %w", err)
+		}
+
+		if attempts == 0 {
+			s.release(ctx, keys[:i])
+			return s.locked(ctx, k.key)
+		}
+
+		if lock := s.lockout(attempts, k.limit); lock > 0 {
+			logger.Log.Warn("Login locked out",
+				zap.String("audit", "login_lockout"),
+				zap.String("key", k.key),
+				zap.String("login", login),
+				zap.String("ip", ip),
+				zap.Int("attempts", attempts),
+				zap.Int("lock_seconds", lock),
+			)
+		}
+	}
+
+	return nil
+}
+
+// Success resets account counter and uncounts the attempt of address. Address counter is
+// not reset, one valid account must not let an attacker keep guessing passwords of others.
+func (s *LoginGuardService) Success(ctx context.Context, login, ip string) error {
+	if err := s.store.ResetLoginAttempts(ctx, loginKey(login)); err != nil {
+		return fmt.Errorf("reset login attempts error: %w", err)
+	}
+	if err := s.store.ReleaseLoginAttempt(ctx, ipKey(ip), s.maxIP); err != nil {
+		return fmt.Errorf("release login attempt error: %w", err)
+	}
+	return nil
+}
+
+// Release uncounts attempt which ended before password was checked
+func (s *LoginGuardService) Release(ctx context.Context, login, ip string) error {
+	for _, k := range s.keys(login, ip) {
+		if err := s.store.ReleaseLoginAttempt(ctx, k.key, k.limit); err != nil {
+			return fmt.Errorf("release login attempt error: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteStaleAttempts drops counters idle longer than failure window. It runs periodically,
+// not on every failed login, as it scans the whole store.
+func (s *LoginGuardService) DeleteStaleAttempts(ctx context.Context) (int64, error) {
+	deleted, err := s.store.DeleteStaleLoginAttempts(ctx, s.window)
+	if err != nil {
+		return 0, fmt.Errorf("delete stale login attempts error: %w", err)
+	}
+	return deleted, nil
+}
+
+// lockout returns lock in seconds: base on reaching limit, doubled for each attempt after it
+func (s *LoginGuardService) lockout(attempts, limit int) int {
+	if limit <= 0 || attempts < limit {
+		return 0
+	}
+
+	lock := s.lockoutBase
+	for i := limit; i < attempts && lock < s.lockoutMax; i++ {
+		lock *= 2
+	}
+	return min(lock, s.lockoutMax)
+}
+
+func (s *LoginGuardService) keys(login, ip string) []guardKey {
+	return []guardKey{
+		{loginKey(login), s.maxLogin},
+		{ipKey(ip), s.maxIP},
+	}
+}
+
+// release uncounts attempt of keys counted before the attempt was rejected
+func (s *LoginGuardService) release(ctx context.Context, keys []guardKey) {
+	for _, k := range keys {
+		if err := s.store.ReleaseLoginAttempt(ctx, k.key, k.limit); err != nil {
+			logger.Log.Error("Failed to release login attempt", zap.String("key", k.key), zap.Error(err))
+		}
+	}
+}
+
+// locked returns LockedError with time left until key is unlocked
+func (s *LoginGuardService) locked(ctx context.Context, key string) error {
+	seconds, err := s.store.GetLoginLock(ctx, key)
+	if err != nil {
+		return fmt.Errorf("check login lock error: %w", err)
+	}
+	return &LockedError{RetryAfter: time.Duration(max(seconds, 1)) * time.Second}
+}
+
+func loginKey(login string) string {
+	return "login:" + login
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,155 @@
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService() *LoginGuardService {
+	return NewLoginGuardService(NewMemoryStore(), &config.Config{
+		LoginMaxAttempts:   3,
+		LoginIPMaxAttempts: 10,
+		LoginLockoutBase:   30,
+		LoginLockoutMax:    100,
+		LoginFailureWindow: 3600,
+	})
+}
+
+func TestLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     int
+	}{
+		{failures: 2, want: 0},
+		{failures: 3, want: 30},
+		{failures: 4, want: 60},
+		{failures: 5, want: 100},
+		{failures: 50, want: 100},
+	}
+
+	s := newTestService()
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, s.lockout(tt.failures, 3), "failures: %d", tt.failures)
+	}
+}
+
+func TestLoginLockedAfterAttempts(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Attempt(ctx, "user", "10.0.0.1"))
+	}
+
+	err := s.Attempt(ctx, "user", "10.0.0.2")
+	assert.True(t, errors.Is(err, errs.ErrTooManyLoginAttempts), err)
+
+	var locked *LockedError
+	require.True(t, errors.As(err, &locked))
+	assert.Equal(t, 30*time.Second, locked.RetryAfter)
+	assert.Equal(t, "30", locked.RetryAfterSeconds())
+
+	assert.NoError(t, s.Attempt(ctx, "other", "10.0.0.1"), "address is below its own limit")
+}
+
+func TestParallelAttemptsLimited(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		passed int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.Attempt(ctx, "user", "10.0.0."+strconv.Itoa(i)) == nil {
+				mu.Lock()
+				passed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, passed, "only attempts below limit are verified")
+}
+
+func TestIPLockedAcrossLogins(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Attempt(ctx, string(rune('a'+i)), "10.0.0.1"))
+	}
+
+	assert.True(t, errors.Is(s.Attempt(ctx, "fresh", "10.0.0.1"), errs.ErrTooManyLoginAttempts))
+	assert.NoError(t, s.Attempt(ctx, "fresh", "10.0.0.2"), "login is not counted when address is locked")
+}
+
+func TestSuccessResetsLoginCounter(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Attempt(ctx, "user", "10.0.0.1"))
+	}
+	require.NoError(t, s.Success(ctx, "user", "10.0.0.1"))
+
+	assert.NoError(t, s.Attempt(ctx, "user", "10.0.0.1"), "lock set by successful attempt is lifted")
+}
+
+func TestSuccessReleasesAddress(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	for i := 0; i < 9; i++ {
+		require.NoError(t, s.Attempt(ctx, string(rune('a'+i)), "10.0.0.1"))
+	}
+	require.NoError(t, s.Attempt(ctx, "user", "10.0.0.1"))
+	require.NoError(t, s.Success(ctx, "user", "10.0.0.1"))
+
+	assert.NoError(t, s.Attempt(ctx, "other", "10.0.0.1"), "successful login is not counted against address")
+}
+
+func TestReleaseUncountsAttempt(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Attempt(ctx, "user", "10.0.0.1"))
+		require.NoError(t, s.Release(ctx, "user", "10.0.0.1"))
+	}
+
+	assert.NoError(t, s.Attempt(ctx, "user", "10.0.0.1"))
+}
+
+func TestMemoryStoreDeleteStale(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	_, err := store.RecordLoginAttempt(ctx, "idle", 3600, func(int) int { return 0 })
+	require.NoError(t, err)
+	_, err = store.RecordLoginAttempt(ctx, "locked", 3600, func(int) int { return 60 })
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+	deleted, err := store.DeleteStaleLoginAttempts(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted, "locked counter is kept")
+
+	seconds, err := store.GetLoginLock(ctx, "locked")
+	require.NoError(t, err)
+	assert.Positive(t, seconds)
+}
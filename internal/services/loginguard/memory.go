@@ -0,0 +1,117 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type attempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// MemoryStore keeps counters in process memory, they are lost on restart
+// and not shared between instances
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*attempts
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*attempts),
+	}
+}
+
+// RecordLoginAttempt counts attempt of unlocked key and locks it for lockout(attempts) seconds.
+// Returns zero if key is locked.
+func (s *MemoryStore) RecordLoginAttempt(_ context.Context, key string, windowSeconds int, lockout func(attempts int) int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	window := time.Duration(windowSeconds) * time.Second
+
+	a, ok := s.entries[key]
+	if !ok {
+		a = &attempts{}
+		s.entries[key] = a
+	}
+
+	if now.Before(a.lockedUntil) {
+		return 0, nil
+	}
+
+	if now.Sub(a.lastFailure) > window {
+		a.failures = 0
+	}
+	a.failures++
+	a.lastFailure = now
+
+	if lock := lockout(a.failures); lock > 0 {
+		a.lockedUntil = now.Add(time.Duration(lock) * time.Second)
+	}
+
+	return a.failures, nil
+}
+
+// ReleaseLoginAttempt uncounts attempt, the lock is lifted once attempts are below limit
+func (s *MemoryStore) ReleaseLoginAttempt(_ context.Context, key string, limit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.entries[key]
+	if !ok || a.failures == 0 {
+		return nil
+	}
+
+	a.failures--
+	if a.failures < limit {
+		a.lockedUntil = time.Time{}
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetLoginLock(_ context.Context, key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.entries[key]
+	if !ok {
+		return 0, nil
+	}
+
+	left := time.Until(a.lockedUntil)
+	if left <= 0 {
+		return 0, nil
+	}
+	return int((left + time.Second - 1) / time.Second), nil
+}
+
+func (s *MemoryStore) ResetLoginAttempts(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// DeleteStaleLoginAttempts drops idle unlocked counters so the map does not grow forever
+func (s *MemoryStore) DeleteStaleLoginAttempts(_ context.Context, windowSeconds int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	window := time.Duration(windowSeconds) * time.Second
+
+	var deleted int64
+	for key, a := range s.entries {
+		if now.Sub(a.lastFailure) > window && now.After(a.lockedUntil) {
+			delete(s.entries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
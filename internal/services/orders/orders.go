@@ -9,18 +9,17 @@ import (
 	"github.com/morzisorn/gofermart/internal/errs"
 	"github.com/morzisorn/gofermart/internal/models"
 	"github.com/morzisorn/gofermart/internal/repositories"
-	"github.com/morzisorn/gofermart/internal/services/users"
 )
 
 type OrderService struct {
-	repo repositories.Repository
-	user users.BalanceGetter
+	repo     repositories.Repository
+	uploaded chan struct{} //Wakes up processing when new order is queued
 }
 
-func NewOrderService(repo repositories.Repository, user users.BalanceGetter) *OrderService {
+func NewOrderService(repo repositories.Repository) *OrderService {
 	return &OrderService{
-		repo: repo,
-		user: user,
+		repo:     repo,
+		uploaded: make(chan struct{}, 1),
 	}
 }
 
@@ -34,9 +33,19 @@ func (os *OrderService) UploadOrder(ctx context.Context, login, number string) e
 		return fmt.Errorf("failed to upload order: %w", err)
 	}
 
+	select {
+	case os.uploaded <- struct{}{}:
+	default:
+	}
+
 	return nil
 }
 
+// Uploaded signals when new orders were queued since last receive
+func (os *OrderService) Uploaded() <-chan struct{} {
+	return os.uploaded
+}
+
 func (os *OrderService) GetUserOrders(ctx context.Context, login string) (*[]models.Order, error) {
 	orders, err := os.repo.GetUserOrders(ctx, login)
 	switch {
@@ -48,8 +57,49 @@ func (os *OrderService) GetUserOrders(ctx context.Context, login string) (*[]mod
 	return orders, nil
 }
 
-func (os *OrderService) GetUpprocessedOrders(ctx context.Context) (*[]models.Order, error) {
-	return os.repo.GetUpprocessedOrders(ctx)
+// ClaimOrderJobs leases up to batchSize due orders to worker for leaseSeconds
+func (os *OrderService) ClaimOrderJobs(ctx context.Context, worker string, batchSize, leaseSeconds int) (*[]models.OrderJob, error) {
+	jobs, err := os.repo.ClaimOrderJobs(ctx, worker, batchSize, leaseSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("claim order jobs error: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetUserOrdersProcessing returns orders of user with attempts, next check and park reason
+func (os *OrderService) GetUserOrdersProcessing(ctx context.Context, login string) (*[]models.OrderProcessing, error) {
+	orders, err := os.repo.GetUserOrdersProcessing(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("get user orders processing error: %w", err)
+	}
+	return orders, nil
+}
+
+// GetParkedOrders returns orders taken out of processing, including stuck ones
+func (os *OrderService) GetParkedOrders(ctx context.Context) (*[]models.OrderProcessing, error) {
+	orders, err := os.repo.GetParkedOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get parked orders error: %w", err)
+	}
+	return orders, nil
+}
+
+// RescheduleOrder returns order to queue to be checked again after delaySeconds
+func (os *OrderService) RescheduleOrder(ctx context.Context, number string, delaySeconds int) error {
+	err := os.repo.RescheduleOrderJob(ctx, number, delaySeconds)
+	if err != nil {
+		return fmt.Errorf("reschedule order error: %w", err)
+	}
+	return nil
+}
+
+// ExtendOrderLeases keeps orders claimed by worker hidden from other replicas for leaseSeconds more
+func (os *OrderService) ExtendOrderLeases(ctx context.Context, worker string, leaseSeconds int) error {
+	err := os.repo.ExtendOrderJobLeases(ctx, worker, leaseSeconds)
+	if err != nil {
+		return fmt.Errorf("extend order leases error: %w", err)
+	}
+	return nil
 }
 
 func (os *OrderService) GetUserWithdrawals(ctx context.Context, login string) (*[]models.Withdrawal, error) {
@@ -64,30 +114,39 @@ func (os *OrderService) GetUserWithdrawals(ctx context.Context, login string) (*
 }
 
 func (os *OrderService) Withdraw(ctx context.Context, login string, w *models.Withdrawal) error {
-	balance, err := os.user.GetBalance(ctx, &models.User{Login: login})
-	if err != nil {
-		return fmt.Errorf("withdrawal error: %w", err)
-	}
-
-	if balance.Current < w.Sum {
-		return fmt.Errorf("withdrawal error: %w", errs.ErrInsufficientBalance)
+	if w.Sum <= 0 {
+		return fmt.Errorf("withdrawal error: %w", errs.ErrIncorrectSum)
 	}
 
 	if !isNumberValid(w.Number) {
 		return fmt.Errorf("withdrawal error: %w", errs.ErrIncorrectNumber)
 	}
 
-	return os.repo.Withdraw(ctx, login, w.Number, w.Sum)
+	// Balance is checked by repository in the same transaction as the debit
+	err := os.repo.Withdraw(ctx, login, w.Number, w.Sum)
+	if err != nil {
+		return fmt.Errorf("withdrawal error: %w", err)
+	}
+	return nil
 }
 
 func (os *OrderService) UpdateOrderStatus(ctx context.Context, number, newStatus string) error {
-	err := os.repo.UpdateOrderStatus(ctx, number, models.OrderStatusPROCESSING)
+	err := os.repo.UpdateOrderStatus(ctx, number, newStatus)
 	if err != nil {
 		return fmt.Errorf("update order status error: %w", err)
 	}
 	return nil
 }
 
+// CompleteOrder removes order with final status from processing queue
+func (os *OrderService) CompleteOrder(ctx context.Context, number string) error {
+	err := os.repo.CompleteOrderJob(ctx, number)
+	if err != nil {
+		return fmt.Errorf("complete order error: %w", err)
+	}
+	return nil
+}
+
 func (os *OrderService) OrderProcessed(ctx context.Context, order models.Order) error {
 	err := os.repo.OrderProcessed(ctx, order.UserLogin, order.Number, order.Accrual)
 	if err != nil {
@@ -96,6 +155,15 @@ func (os *OrderService) OrderProcessed(ctx context.Context, order models.Order)
 	return nil
 }
 
+// ParkOrder moves order out of processing loop until an operator looks at it
+func (os *OrderService) ParkOrder(ctx context.Context, number, reason string) error {
+	err := os.repo.ParkOrder(ctx, number, reason)
+	if err != nil {
+		return fmt.Errorf("park order error: %w", err)
+	}
+	return nil
+}
+
 // Luhn algorithm
 func isNumberValid(number string) bool {
 	numR := []rune(number)
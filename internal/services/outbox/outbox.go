@@ -0,0 +1,104 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/logger"
+	"github.com/morzisorn/gofermart/internal/models"
+	"go.uber.org/zap"
+)
+
+type Store interface {
+	ClaimOutboxEvents(ctx context.Context, batchSize, leaseSeconds int) (*[]models.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	FailOutboxEvent(ctx context.Context, id int64, delaySeconds int, reason string) error
+	DeletePublishedOutboxEvents(ctx context.Context, retentionSeconds int) error
+}
+
+// RelayService publishes events written to outbox by order and balance transactions.
+// Replicas share the outbox, every event is leased by one of them at a time.
+type RelayService struct {
+	store Store
+	sink  Sink
+
+	batchSize  int
+	lease      int
+	backoffMax int
+	retention  int
+}
+
+func NewRelayService(store Store, sink Sink, cnfg *config.Config) *RelayService {
+	return &RelayService{
+		store:      store,
+		sink:       sink,
+		batchSize:  cnfg.OutboxBatchSize,
+		lease:      cnfg.OutboxLease,
+		backoffMax: cnfg.OutboxBackoffMax,
+		retention:  cnfg.OutboxRetention,
+	}
+}
+
+// Relay publishes due events batch by batch until none are left
+func (rs *RelayService) Relay(ctx context.Context) error {
+	if err := rs.store.DeletePublishedOutboxEvents(ctx, rs.retention); err != nil {
+		return fmt.Errorf("relay events error: %w", err)
+	}
+
+	for ctx.Err() == nil {
+		events, err := rs.store.ClaimOutboxEvents(ctx, rs.batchSize, rs.lease)
+		if err != nil && ctx.Err() != nil {
+			// Shutting down, claimed events return to outbox when lease expires
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("relay events error: %w", err)
+		}
+
+		for i := range *events {
+			if ctx.Err() != nil {
+				return nil
+			}
+			rs.publish(ctx, &(*events)[i])
+		}
+
+		if len(*events) < rs.batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// publish leaves failed event in outbox, it is retried with delay doubling from one second
+func (rs *RelayService) publish(ctx context.Context, event *models.OutboxEvent) {
+	if err := rs.sink.Publish(ctx, event); err != nil {
+		delay := rs.backoff(event.Attempts)
+		logger.Log.Warn("Failed to publish event",
+			zap.Int64("id", event.ID),
+			zap.String("type", event.Type),
+			zap.Int("attempts", event.Attempts),
+			zap.Int("retry_in", delay),
+			zap.Error(err),
+		)
+
+		if err := rs.store.FailOutboxEvent(ctx, event.ID, delay, err.Error()); err != nil {
+			logger.Log.Error("Failed to reschedule event", zap.Int64("id", event.ID), zap.Error(err))
+		}
+		return
+	}
+
+	// Event stays leased if this fails and is published again when lease expires
+	if err := rs.store.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+		logger.Log.Error("Failed to mark event published", zap.Int64("id", event.ID), zap.Error(err))
+	}
+}
+
+func (rs *RelayService) backoff(attempts int) int {
+	delay := 1
+	for i := 1; i < attempts && delay < rs.backoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, rs.backoffMax)
+}
@@ -0,0 +1,92 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	pending   []models.OutboxEvent
+	published []int64
+	failed    map[int64]int
+}
+
+func (s *memoryStore) ClaimOutboxEvents(_ context.Context, batchSize, _ int) (*[]models.OutboxEvent, error) {
+	n := min(batchSize, len(s.pending))
+	claimed := s.pending[:n]
+	s.pending = s.pending[n:]
+	for i := range claimed {
+		claimed[i].Attempts++
+	}
+	return &claimed, nil
+}
+
+func (s *memoryStore) MarkOutboxEventPublished(_ context.Context, id int64) error {
+	s.published = append(s.published, id)
+	return nil
+}
+
+func (s *memoryStore) FailOutboxEvent(_ context.Context, id int64, delaySeconds int, _ string) error {
+	s.failed[id] = delaySeconds
+	return nil
+}
+
+func (s *memoryStore) DeletePublishedOutboxEvents(context.Context, int) error {
+	return nil
+}
+
+type flakySink struct {
+	received []int64
+	fail     map[int64]bool
+}
+
+func (s *flakySink) Publish(_ context.Context, event *models.OutboxEvent) error {
+	if s.fail[event.ID] {
+		return errors.New("connection refused")
+	}
+	s.received = append(s.received, event.ID)
+	return nil
+}
+
+func TestRelay(t *testing.T) {
+	store := &memoryStore{failed: make(map[int64]int)}
+	for id := int64(1); id <= 5; id++ {
+		store.pending = append(store.pending, models.OutboxEvent{ID: id, Type: models.OutboxEventOrderProcessed})
+	}
+	store.pending[2].Attempts = 3
+
+	sink := &flakySink{fail: map[int64]bool{3: true}}
+
+	rs := NewRelayService(store, sink, &config.Config{OutboxBatchSize: 2, OutboxBackoffMax: 300})
+	require.NoError(t, rs.Relay(context.Background()))
+
+	assert.Empty(t, store.pending, "relay drains outbox batch by batch")
+	assert.Equal(t, []int64{1, 2, 4, 5}, sink.received, "failed event does not block next ones")
+	assert.Equal(t, []int64{1, 2, 4, 5}, store.published)
+	assert.Equal(t, map[int64]int{3: 8}, store.failed, "delay doubles with attempts")
+}
+
+func TestRelayBackoff(t *testing.T) {
+	rs := &RelayService{backoffMax: 60}
+
+	tests := []struct {
+		attempts int
+		want     int
+	}{
+		{1, 1},
+		{2, 2},
+		{5, 16},
+		{7, 60},
+		{100, 60},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, rs.backoff(tt.attempts), "attempts %d", tt.attempts)
+	}
+}
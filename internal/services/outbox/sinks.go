@@ -0,0 +1,119 @@
+package outbox
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/models"
+	"resty.dev/v3"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// Sink delivers event outside gofermart. Delivery is at least once,
+// consumers deduplicate by event ID.
+type Sink interface {
+	Publish(ctx context.Context, event *models.OutboxEvent) error
+}
+
+// NewSink returns sink chosen by OutboxSink, nil when events are kept in outbox
+func NewSink(cnfg *config.Config) Sink {
+	switch cnfg.OutboxSink {
+	case config.OutboxSinkWebhook:
+		return NewWebhookSink(cnfg.OutboxWebhookURL, cnfg.OutboxWebhookSecret)
+	case config.OutboxSinkFile:
+		return NewFileSink(cnfg.OutboxFilePath)
+	default:
+		return nil
+	}
+}
+
+// WebhookSink POSTs every event as JSON, any 2xx response means delivered
+type WebhookSink struct {
+	url    string
+	secret []byte
+	client *resty.Client
+}
+
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		secret: []byte(secret),
+		client: resty.New().SetTimeout(webhookTimeout),
+	}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event error: %w", err)
+	}
+
+	req := s.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Event-ID", strconv.FormatInt(event.ID, 10)).
+		SetHeader("X-Event-Type", event.Type).
+		SetBody(body)
+
+	if len(s.secret) > 0 {
+		req.SetHeader("X-Signature", "sha256="+sign(s.secret, body))
+	}
+
+	resp, err := req.Post(s.url)
+	if err != nil {
+		return fmt.Errorf("webhook request error: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return fmt.Errorf("webhook responded %d", resp.StatusCode())
+	}
+	return nil
+}
+
+// sign returns hex HMAC-SHA256 of body, receivers recompute it to check the sender
+func sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// FileSink appends events to NDJSON file, one event per line. Meant for local testing.
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+func (s *FileSink) Publish(_ context.Context, event *models.OutboxEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event error: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// File is reopened for every event, so it can be rotated or removed while running
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open outbox file error: %w", err)
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write outbox file error: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,78 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent(id int64) *models.OutboxEvent {
+	return &models.OutboxEvent{
+		ID:      id,
+		Type:    models.OutboxEventWithdrawal,
+		Key:     "2377225624",
+		Payload: json.RawMessage(`{"order":"2377225624","user_login":"user","sum":751}`),
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		body   []byte
+		header http.Header
+		status = http.StatusOK
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, "secret")
+	require.NoError(t, sink.Publish(context.Background(), testEvent(7)))
+
+	assert.Equal(t, "7", header.Get("X-Event-ID"))
+	assert.Equal(t, models.OutboxEventWithdrawal, header.Get("X-Event-Type"))
+	assert.Equal(t, "sha256="+sign([]byte("secret"), body), header.Get("X-Signature"))
+
+	var got models.OutboxEvent
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, int64(7), got.ID)
+	assert.JSONEq(t, string(testEvent(7).Payload), string(got.Payload))
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, sink.Publish(context.Background(), testEvent(8)))
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.ndjson")
+	sink := NewFileSink(path)
+
+	for id := int64(1); id <= 3; id++ {
+		require.NoError(t, sink.Publish(context.Background(), testEvent(id)))
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var ids []int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e models.OutboxEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		ids = append(ids, e.ID)
+	}
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+}
@@ -0,0 +1,19 @@
+package processing
+
+import "github.com/morzisorn/gofermart/config"
+
+// backoff returns delay in seconds before next check of order claimed attempts times
+func backoff(policy string, base, maxDelay, attempts int) int {
+	delay := base
+
+	switch policy {
+	case config.OrderBackoffLinear:
+		delay = base * max(attempts, 1)
+	case config.OrderBackoffExponential:
+		for i := 1; i < attempts && delay < maxDelay; i++ {
+			delay *= 2
+		}
+	}
+
+	return min(delay, maxDelay)
+}
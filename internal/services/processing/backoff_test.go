@@ -0,0 +1,32 @@
+package processing
+
+import (
+	"testing"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		attempts int
+		want     int
+	}{
+		{"constant first", config.OrderBackoffConstant, 1, 5},
+		{"constant later", config.OrderBackoffConstant, 10, 5},
+		{"linear first", config.OrderBackoffLinear, 1, 5},
+		{"linear third", config.OrderBackoffLinear, 3, 15},
+		{"linear capped", config.OrderBackoffLinear, 100, 60},
+		{"exponential first", config.OrderBackoffExponential, 1, 5},
+		{"exponential third", config.OrderBackoffExponential, 3, 20},
+		{"exponential capped", config.OrderBackoffExponential, 50, 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, backoff(tt.policy, 5, 60, tt.attempts))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package processing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/models"
+)
+
+// Breaker spaces out processing runs after failures: delay doubles with every failure in a row,
+// and after threshold failures circuit opens for the maximum delay, then one trial run is let through.
+type Breaker struct {
+	threshold int
+	base      time.Duration
+	max       time.Duration
+
+	mu       sync.Mutex
+	failures int
+	retryAt  time.Time
+}
+
+func NewBreaker(cnfg *config.Config) *Breaker {
+	return &Breaker{
+		threshold: cnfg.ProcessingBreakerThreshold,
+		base:      time.Duration(cnfg.ProcessingBackoffBase) * time.Second,
+		max:       time.Duration(cnfg.ProcessingBackoffMax) * time.Second,
+	}
+}
+
+// Allow reports whether backoff after last failure has passed
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !time.Now().Before(b.retryAt)
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.retryAt = time.Time{}
+}
+
+// Failure records failed run and returns delay before the next one.
+// Error itself is logged by the caller, status shown by health check does not carry it.
+func (b *Breaker) Failure() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+
+	delay := b.base
+	for i := 1; i < b.failures && delay < b.max; i++ {
+		delay *= 2
+	}
+	if b.isOpen() {
+		delay = b.max
+	}
+	delay = min(delay, b.max)
+
+	b.retryAt = time.Now().Add(delay)
+	return delay
+}
+
+func (b *Breaker) Status() models.ProcessingStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := models.ProcessingStatus{
+		State:    models.BreakerClosed,
+		Failures: b.failures,
+	}
+
+	if b.isOpen() {
+		status.State = models.BreakerHalfOpen
+		if time.Now().Before(b.retryAt) {
+			status.State = models.BreakerOpen
+		}
+	}
+
+	if b.failures > 0 {
+		retryAt := b.retryAt
+		status.RetryAt = &retryAt
+	}
+
+	return status
+}
+
+// isOpen reports whether failures reached threshold, b.mu must be held
+func (b *Breaker) isOpen() bool {
+	return b.threshold > 0 && b.failures >= b.threshold
+}
@@ -0,0 +1,36 @@
+package processing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	b := NewBreaker(&config.Config{
+		ProcessingBreakerThreshold: 3,
+		ProcessingBackoffBase:      1,
+		ProcessingBackoffMax:       60,
+	})
+
+	assert.True(t, b.Allow())
+	assert.Equal(t, models.BreakerClosed, b.Status().State)
+
+	assert.Equal(t, time.Second, b.Failure())
+	assert.False(t, b.Allow())
+	assert.Equal(t, 2*time.Second, b.Failure())
+	assert.Equal(t, models.BreakerClosed, b.Status().State)
+
+	assert.Equal(t, 60*time.Second, b.Failure(), "circuit opens at threshold")
+	status := b.Status()
+	assert.Equal(t, models.BreakerOpen, status.State)
+	assert.Equal(t, 3, status.Failures)
+	assert.NotNil(t, status.RetryAt)
+
+	b.Success()
+	assert.True(t, b.Allow())
+	assert.Equal(t, models.ProcessingStatus{State: models.BreakerClosed}, b.Status())
+}
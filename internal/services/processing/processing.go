@@ -2,7 +2,11 @@ package processing
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/morzisorn/gofermart/config"
 	"github.com/morzisorn/gofermart/internal/client"
@@ -12,27 +16,92 @@ import (
 	"go.uber.org/zap"
 )
 
+// maxThrottleRetries is how many times one order is sent to throttling accrual before it is rescheduled
+const maxThrottleRetries = 3
+
 type ProcessingService struct {
 	service *orders.OrderService
 	client  client.LoyaltyClient
+	worker  string //Lease owner of claimed jobs, unique per replica
+
+	mu          sync.Mutex
+	pausedUntil time.Time //All loyalty workers wait until accrual throttling window passes
 }
 
 func NewProcessingService(service *orders.OrderService, client client.LoyaltyClient) *ProcessingService {
+	host, _ := os.Hostname()
+
 	return &ProcessingService{
 		service: service,
 		client:  client,
+		worker:  fmt.Sprintf("%s:%d", host, os.Getpid()),
 	}
 }
 
+// ProcessOrders claims due orders from processing queue batch by batch until none are left
 func (ps *ProcessingService) ProcessOrders(ctx context.Context) error {
-	chIn := ps.ordersProducer(ctx)
+	cnfg := config.GetConfig()
+
+	for ctx.Err() == nil {
+		jobs, err := ps.service.ClaimOrderJobs(ctx, ps.worker, cnfg.JobBatchSize, cnfg.JobLease)
+		if err != nil && ctx.Err() != nil {
+			// Shutting down, claimed jobs return to queue when lease expires
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("process orders error: %w", err)
+		}
+
+		stopRenewal := ps.renewLeases(ctx, cnfg.JobLease)
+		ps.processBatch(ctx, jobs, cnfg.RateLimit)
+		stopRenewal()
+
+		if len(*jobs) < cnfg.JobBatchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// renewLeases extends leases of claimed jobs every third of lease until returned func is called.
+// Throttled accrual can hold a batch longer than any lease, other replicas must not claim it meanwhile.
+func (ps *ProcessingService) renewLeases(ctx context.Context, leaseSeconds int) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(time.Duration(leaseSeconds) * time.Second / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := ps.service.ExtendOrderLeases(ctx, ps.worker, leaseSeconds); err != nil && ctx.Err() == nil {
+				logger.Log.Error("Failed to extend order leases", zap.Error(err))
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (ps *ProcessingService) processBatch(ctx context.Context, jobs *[]models.OrderJob, rateLimit int) {
+	chIn := ps.ordersProducer(jobs)
 
 	var wg sync.WaitGroup
 	var loyaltyWg sync.WaitGroup
 
-	rateLimit := config.GetConfig().RateLimit
-
-	chLoyaltyUpdates := make(chan models.Order, 10)
+	chLoyaltyUpdates := make(chan models.OrderJob, 10)
 
 	ps.runLoyaltyWorkers(ctx, chIn, chLoyaltyUpdates, &loyaltyWg, rateLimit)
 
@@ -44,81 +113,231 @@ func (ps *ProcessingService) ProcessOrders(ctx context.Context) error {
 	ps.runUpdateWorker(ctx, chLoyaltyUpdates, &wg, rateLimit)
 
 	wg.Wait()
-
-	return nil
 }
 
-func (ps *ProcessingService) ordersProducer(ctx context.Context) chan models.Order {
-	orders, err := ps.service.GetUpprocessedOrders(ctx)
-	if err != nil {
-		logger.Log.Panic("Failed to get unprocessed orders")
-	}
-
-	ch := make(chan models.Order, len(*orders))
+func (ps *ProcessingService) ordersProducer(jobs *[]models.OrderJob) chan models.OrderJob {
+	ch := make(chan models.OrderJob, len(*jobs))
 
 	go func() {
 		defer close(ch)
-		for _, o := range *orders {
-			ch <- o
+		for _, j := range *jobs {
+			ch <- j
 		}
 	}()
 
 	return ch
 }
 
-func (ps *ProcessingService) runLoyaltyWorkers(ctx context.Context, chIn chan models.Order, chOut chan models.Order, wg *sync.WaitGroup, rateLimit int) {
+// retry returns order to queue with backoff by its attempts, or parks it as stuck when it is older than OrderMaxAge
+func (ps *ProcessingService) retry(ctx context.Context, j *models.OrderJob) {
+	cnfg := config.GetConfig()
+	o := j.Order
+
+	if cnfg.OrderMaxAge > 0 && j.Age > time.Duration(cnfg.OrderMaxAge)*time.Second {
+		reason := fmt.Sprintf("stuck: still %s after %d checks in %s", o.Status, j.Attempts, j.Age.Round(time.Second))
+		if err := ps.service.ParkOrder(ctx, o.Number, reason); err != nil {
+			logger.Log.Error("Failed to park order", zap.String("number", o.Number), zap.Error(err))
+		} else {
+			logger.Log.Warn("Order parked: stuck in processing", zap.String("number", o.Number), zap.Int("attempts", j.Attempts))
+		}
+		return
+	}
+
+	delay := backoff(cnfg.OrderBackoff, cnfg.OrderBackoffBase, cnfg.OrderBackoffMax, j.Attempts)
+	if err := ps.service.RescheduleOrder(ctx, o.Number, delay); err != nil {
+		logger.Log.Error("Failed to reschedule order", zap.String("number", o.Number), zap.Error(err))
+	}
+}
+
+func (ps *ProcessingService) runLoyaltyWorkers(ctx context.Context, chIn chan models.OrderJob, chOut chan models.OrderJob, wg *sync.WaitGroup, rateLimit int) {
 	for w := 0; w < rateLimit; w++ {
 		wg.Add(1)
 		go ps.loyaltyJob(ctx, chIn, chOut, wg)
 	}
 }
 
-func (ps *ProcessingService) loyaltyJob(ctx context.Context, chIn chan models.Order, chOut chan models.Order, wg *sync.WaitGroup) {
+func (ps *ProcessingService) loyaltyJob(ctx context.Context, chIn chan models.OrderJob, chOut chan models.OrderJob, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	for o := range chIn {
-		lo, err := ps.client.CalculateBonuses(ctx, o.Number)
+	for j := range chIn {
+		if ctx.Err() != nil {
+			return
+		}
+
+		o := &j.Order
+
+		// Job outlived its order, e.g. status was finished by a replica whose lease had expired
+		if models.IsFinalOrderStatus(o.Status) {
+			if err := ps.service.CompleteOrder(ctx, o.Number); err != nil {
+				logger.Log.Error("Failed to complete order", zap.String("number", o.Number), zap.Error(err))
+			}
+			continue
+		}
+
+		lo, err := ps.calculateBonuses(ctx, o.Number)
+		if ctx.Err() != nil {
+			return
+		}
 		if err != nil {
 			logger.Log.Error("Failed to calculate bonuses. ", zap.String("Order number: %s", o.Number))
+			ps.retry(ctx, &j)
 			continue
 		}
 
-		switch lo.Status {
-		case models.LoyaltyStatusREGISTERED:
-			if o.Status == models.OrderStatusPROCESSING {
+		if lo.Status == models.LoyaltyStatusNOTREGISTERED {
+			if !ps.handleUnregistered(ctx, &j) {
 				continue
 			}
-			o.Status = models.OrderStatusPROCESSING
-		case models.LoyaltyStatusPROCESSING:
-			if o.Status == models.OrderStatusNEW {
-				o.Status = models.OrderStatusPROCESSING
+		} else {
+			changed, err := applyLoyalty(o, lo)
+			if err != nil {
+				logger.Log.Error("Failed to apply accrual status", zap.String("number", o.Number), zap.Error(err))
 			}
-		case models.LoyaltyStatusINVALID:
-			o.Status = models.OrderStatusINVALID
-		case models.OrderStatusPROCESSED:
-			o.Accrual = lo.Accrual
-			o.Status = models.OrderStatusPROCESSED
+			if !changed {
+				ps.retry(ctx, &j)
+				continue
+			}
+		}
+
+		chOut <- j
+	}
+}
+
+// applyLoyalty moves order to status reported by accrual and reports whether it changed.
+// Status that order can not move to, e.g. the current one, leaves order as is.
+func applyLoyalty(o *models.Order, lo *models.LoyaltyOrder) (bool, error) {
+	status, ok := models.OrderStatusFromLoyalty(lo.Status)
+	if !ok {
+		return false, fmt.Errorf("unknown accrual status: %s", lo.Status)
+	}
+
+	if !models.CanTransitionOrder(o.Status, status) {
+		return false, nil
+	}
+
+	o.Status = status
+	if status == models.OrderStatusPROCESSED {
+		o.Accrual = lo.Accrual
+	}
+	return true, nil
+}
+
+// handleUnregistered applies unregistered order policy and reports whether order must be sent to update workers
+func (ps *ProcessingService) handleUnregistered(ctx context.Context, j *models.OrderJob) bool {
+	cnfg := config.GetConfig()
+	o := &j.Order
+
+	grace := time.Duration(cnfg.UnregisteredGracePeriod) * time.Second
+	if j.Age < grace {
+		ps.retry(ctx, j)
+		return false
+	}
+
+	switch cnfg.UnregisteredPolicy {
+	case config.UnregisteredPolicyPark:
+		if err := ps.service.ParkOrder(ctx, o.Number, "not registered in accrual system"); err != nil {
+			logger.Log.Error("Failed to park order", zap.String("number", o.Number), zap.Error(err))
+		} else {
+			logger.Log.Warn("Order parked: not registered in accrual system", zap.String("number", o.Number))
+		}
+		return false
+	default:
+		o.Status = models.OrderStatusINVALID
+		return true
+	}
+}
+
+// calculateBonuses waits out accrual throttling. After maxThrottleRetries throttled
+// requests the error is returned, so the order is rescheduled and the worker moves on.
+func (ps *ProcessingService) calculateBonuses(ctx context.Context, number string) (*models.LoyaltyOrder, error) {
+	for attempt := 1; ; attempt++ {
+		if err := ps.waitPause(ctx); err != nil {
+			return nil, err
 		}
 
-		chOut <- o
+		lo, err := ps.client.CalculateBonuses(ctx, number)
+
+		var throttle *client.ThrottleError
+		if !errors.As(err, &throttle) {
+			return lo, err
+		}
+
+		logger.Log.Warn("Accrual system throttled requests, pausing workers",
+			zap.Duration("retry_after", throttle.RetryAfter),
+			zap.Int("limit", throttle.Limit),
+		)
+		ps.pause(throttle.RetryAfter)
+
+		if attempt >= maxThrottleRetries {
+			return nil, err
+		}
+	}
+}
+
+func (ps *ProcessingService) pause(d time.Duration) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(ps.pausedUntil) {
+		ps.pausedUntil = until
 	}
 }
 
-func (ps *ProcessingService) runUpdateWorker(ctx context.Context, chIn chan models.Order, wg *sync.WaitGroup, rateLimit int) {
+func (ps *ProcessingService) waitPause(ctx context.Context) error {
+	for {
+		ps.mu.Lock()
+		wait := time.Until(ps.pausedUntil)
+		ps.mu.Unlock()
+
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (ps *ProcessingService) runUpdateWorker(ctx context.Context, chIn chan models.OrderJob, wg *sync.WaitGroup, rateLimit int) {
 	for w := 0; w < rateLimit; w++ {
 		wg.Add(1)
 		go ps.updateOrdersJob(ctx, chIn, wg)
 	}
 }
 
-func (ps *ProcessingService) updateOrdersJob(ctx context.Context, chIn chan models.Order, wg *sync.WaitGroup) {
+func (ps *ProcessingService) updateOrdersJob(ctx context.Context, chIn chan models.OrderJob, wg *sync.WaitGroup) {
 	defer wg.Done()
-	for o := range chIn {
+
+	// Results already received from accrual are saved even on shutdown
+	ctx = context.WithoutCancel(ctx)
+	for j := range chIn {
+		o := j.Order
+		var err error
+
 		switch o.Status {
 		case models.OrderStatusPROCESSED:
-			_ = ps.service.OrderProcessed(ctx, o)
+			// Job is completed in the same transaction
+			err = ps.service.OrderProcessed(ctx, o)
+		case models.OrderStatusINVALID:
+			err = ps.service.UpdateOrderStatus(ctx, o.Number, o.Status)
+			if err == nil {
+				err = ps.service.CompleteOrder(ctx, o.Number)
+			}
 		default:
-			_ = ps.service.UpdateOrderStatus(ctx, o.Number, o.Status)
+			err = ps.service.UpdateOrderStatus(ctx, o.Number, o.Status)
+			if err == nil {
+				ps.retry(ctx, &j)
+			}
+		}
+
+		// Failed update is retried when lease expires
+		if err != nil {
+			logger.Log.Error("Failed to update order", zap.String("number", o.Number), zap.Error(err))
 		}
 	}
 }
@@ -0,0 +1,164 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/client"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/morzisorn/gofermart/internal/repositories"
+	"github.com/morzisorn/gofermart/internal/services/orders"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyLoyalty(t *testing.T) {
+	accrual := models.NewMoney(500, 0)
+
+	tests := []struct {
+		name        string
+		status      string
+		loyalty     string
+		wantChanged bool
+		wantStatus  string
+		wantAccrual models.Money
+		wantErr     bool
+	}{
+		{"registered new order", models.OrderStatusNEW, models.LoyaltyStatusREGISTERED, true, models.OrderStatusPROCESSING, 0, false},
+		{"processing new order", models.OrderStatusNEW, models.LoyaltyStatusPROCESSING, true, models.OrderStatusPROCESSING, 0, false},
+		{"still processing", models.OrderStatusPROCESSING, models.LoyaltyStatusPROCESSING, false, models.OrderStatusPROCESSING, 0, false},
+		{"registered again", models.OrderStatusPROCESSING, models.LoyaltyStatusREGISTERED, false, models.OrderStatusPROCESSING, 0, false},
+		{"processed", models.OrderStatusPROCESSING, models.LoyaltyStatusPROCESSED, true, models.OrderStatusPROCESSED, accrual, false},
+		{"processed at first check", models.OrderStatusNEW, models.LoyaltyStatusPROCESSED, true, models.OrderStatusPROCESSED, accrual, false},
+		{"invalid", models.OrderStatusPROCESSING, models.LoyaltyStatusINVALID, true, models.OrderStatusINVALID, 0, false},
+		{"already processed", models.OrderStatusPROCESSED, models.LoyaltyStatusPROCESSED, false, models.OrderStatusPROCESSED, 0, false},
+		{"invalid after processed", models.OrderStatusPROCESSED, models.LoyaltyStatusINVALID, false, models.OrderStatusPROCESSED, 0, false},
+		{"unknown accrual status", models.OrderStatusNEW, "UNKNOWN", false, models.OrderStatusNEW, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &models.Order{Number: "12345678903", Status: tt.status}
+
+			changed, err := applyLoyalty(o, &models.LoyaltyOrder{Order: o.Number, Status: tt.loyalty, Accrual: accrual})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantChanged, changed)
+			assert.Equal(t, tt.wantStatus, o.Status)
+			assert.Equal(t, tt.wantAccrual, o.Accrual)
+		})
+	}
+}
+
+type throttlingClient struct {
+	calls int
+}
+
+func (c *throttlingClient) CalculateBonuses(_ context.Context, _ string) (*models.LoyaltyOrder, error) {
+	c.calls++
+	return nil, &client.ThrottleError{RetryAfter: time.Millisecond}
+}
+
+func TestCalculateBonusesGivesUpWhenThrottled(t *testing.T) {
+	c := &throttlingClient{}
+	ps := NewProcessingService(nil, c)
+
+	_, err := ps.calculateBonuses(context.Background(), "79927398713")
+
+	var throttle *client.ThrottleError
+	assert.True(t, errors.As(err, &throttle), err)
+	assert.Equal(t, maxThrottleRetries, c.calls)
+}
+
+// stubRepository records how retry returned orders, other methods are not used
+type stubRepository struct {
+	repositories.Repository
+	parked      map[string]string
+	rescheduled map[string]int
+
+	mu       sync.Mutex
+	renewals int
+}
+
+func (r *stubRepository) ParkOrder(_ context.Context, number, reason string) error {
+	r.parked[number] = reason
+	return nil
+}
+
+func (r *stubRepository) RescheduleOrderJob(_ context.Context, number string, delaySeconds int) error {
+	r.rescheduled[number] = delaySeconds
+	return nil
+}
+
+func (r *stubRepository) ExtendOrderJobLeases(_ context.Context, _ string, _ int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.renewals++
+	return nil
+}
+
+func (r *stubRepository) renewed() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.renewals
+}
+
+func TestRenewLeases(t *testing.T) {
+	repo := &stubRepository{}
+	ps := NewProcessingService(orders.NewOrderService(repo), nil)
+
+	stop := ps.renewLeases(context.Background(), 1)
+	time.Sleep(500 * time.Millisecond)
+	stop()
+
+	renewed := repo.renewed()
+	assert.Positive(t, renewed, "leases are renewed while batch is in flight")
+
+	time.Sleep(400 * time.Millisecond)
+	assert.Equal(t, renewed, repo.renewed(), "renewal stops with the batch")
+}
+
+func TestRetry(t *testing.T) {
+	maxAge := time.Duration(config.GetConfig().OrderMaxAge) * time.Second
+
+	tests := []struct {
+		name       string
+		age        time.Duration
+		wantParked bool
+	}{
+		{name: "rescheduled", age: time.Hour},
+		{name: "stuck past max age", age: maxAge + time.Hour, wantParked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubRepository{parked: map[string]string{}, rescheduled: map[string]int{}}
+			ps := NewProcessingService(orders.NewOrderService(repo), nil)
+
+			j := &models.OrderJob{
+				Order: models.Order{
+					Number: "79927398713",
+					Status: models.OrderStatusPROCESSING,
+				},
+				Attempts: 40,
+				Age:      tt.age,
+			}
+			ps.retry(context.Background(), j)
+
+			if tt.wantParked {
+				assert.Contains(t, repo.parked[j.Order.Number], "stuck")
+				assert.NotContains(t, repo.rescheduled, j.Order.Number)
+				return
+			}
+			assert.NotContains(t, repo.parked, j.Order.Number)
+			assert.Positive(t, repo.rescheduled[j.Order.Number])
+		})
+	}
+}
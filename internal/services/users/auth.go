@@ -5,18 +5,20 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/keyring"
 )
 
-func generateToken(login string) (string, error) {
+// generateToken issues short-lived access token bound to the session,
+// so revoking the session invalidates the token before it expires
+func generateToken(login, role, sessionID string, ttlSeconds int) (string, error) {
 	claims := jwt.MapClaims{
 		"login": login,
-		"exp":   time.Now().Add(7 * time.Hour * 24).Unix(),
+		"role":  role,
+		"sid":   sessionID,
+		"exp":   time.Now().Add(time.Duration(ttlSeconds) * time.Second).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	cnfg := config.GetConfig()
-	signedToken, err := token.SignedString([]byte(cnfg.SecretKey))
+	signedToken, err := keyring.GetKeyring().Sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("generate token error: %w", err)
 	}
@@ -6,8 +6,19 @@ import (
 	"github.com/morzisorn/gofermart/internal/models"
 )
 
-type BalanceGetter interface {
-	GetBalance(ctx context.Context, user *models.User) (*models.UserBalance, error)
+type Validator interface {
+	ValidateRegistration(user *models.ParseUserRegister) error
+	ValidatePasswordChange(login string, req *models.ChangePassword) error
+}
+
+type LoginGuard interface {
+	Attempt(ctx context.Context, login, ip string) error
+	Success(ctx context.Context, login, ip string) error
+	Release(ctx context.Context, login, ip string) error
+}
+
+type LedgerBalanceGetter interface {
+	GetBalance(ctx context.Context, login string) (*models.UserBalance, error)
 }
 
 
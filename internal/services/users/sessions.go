@@ -0,0 +1,154 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/logger"
+	"github.com/morzisorn/gofermart/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	sessionIDLength    = 16
+	refreshTokenLength = 32
+)
+
+// createSession stores new session with hashed refresh token and issues token pair for it
+func (us *UserService) createSession(ctx context.Context, login, role string) (*models.Tokens, error) {
+	cnfg := config.GetConfig()
+
+	id, err := randomHex(sessionIDLength)
+	if err != nil {
+		return nil, fmt.Errorf("create session error: %w", err)
+	}
+
+	refresh, err := randomHex(refreshTokenLength)
+	if err != nil {
+		return nil, fmt.Errorf("create session error: %w", err)
+	}
+
+	err = us.repo.CreateSession(ctx, id, login, hashRefreshToken(refresh), cnfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("create session error: %w", err)
+	}
+
+	return issueTokens(login, role, id, refresh)
+}
+
+// RefreshSession exchanges refresh token for a new pair. Old refresh token stops working.
+// Presenting already rotated token means it was stolen, so the whole session is revoked.
+func (us *UserService) RefreshSession(ctx context.Context, refreshToken string) (*models.Tokens, error) {
+	cnfg := config.GetConfig()
+
+	refreshHash := hashRefreshToken(refreshToken)
+
+	session, err := us.repo.GetSessionByRefreshHash(ctx, refreshHash)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("refresh session error: %w", errs.ErrInvalidRefreshToken)
+	case err != nil:
+		return nil, fmt.Errorf("refresh session error: %w", err)
+	case session.Revoked || session.Expired:
+		return nil, fmt.Errorf("refresh session error: %w", errs.ErrInvalidRefreshToken)
+	}
+
+	// Role could change since the session started
+	user, err := us.GetUser(ctx, &models.User{Login: session.UserLogin})
+	switch {
+	case errors.Is(err, errs.ErrUserNotFound):
+		return nil, fmt.Errorf("refresh session error: %w", errs.ErrInvalidRefreshToken)
+	case err != nil:
+		return nil, fmt.Errorf("refresh session error: %w", err)
+	}
+
+	newRefresh, err := randomHex(refreshTokenLength)
+	if err != nil {
+		return nil, fmt.Errorf("refresh session error: %w", err)
+	}
+
+	rotated, err := us.repo.RotateSession(ctx, session.ID, refreshHash, hashRefreshToken(newRefresh), cnfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("refresh session error: %w", err)
+	}
+	if !rotated {
+		logger.Log.Warn("Rotated refresh token reused, revoking session",
+			zap.String("login", session.UserLogin),
+			zap.String("session", session.ID),
+		)
+		if err := us.repo.RevokeSession(ctx, session.ID); err != nil {
+			return nil, fmt.Errorf("refresh session error: %w", err)
+		}
+		return nil, fmt.Errorf("refresh session error: %w", errs.ErrInvalidRefreshToken)
+	}
+
+	return issueTokens(user.Login, user.Role, session.ID, newRefresh)
+}
+
+func (us *UserService) Logout(ctx context.Context, sessionID string) error {
+	if err := us.repo.RevokeSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("logout error: %w", err)
+	}
+	return nil
+}
+
+// RevokeOtherSessions logs user out everywhere except the current session
+func (us *UserService) RevokeOtherSessions(ctx context.Context, login, currentSessionID string) error {
+	if err := us.repo.RevokeUserSessions(ctx, login, currentSessionID); err != nil {
+		return fmt.Errorf("revoke sessions error: %w", err)
+	}
+	return nil
+}
+
+func (us *UserService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	active, err := us.repo.IsSessionActive(ctx, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("check session error: %w", err)
+	}
+	return active, nil
+}
+
+// DeleteStaleSessions removes sessions that can not be refreshed anymore
+func (us *UserService) DeleteStaleSessions(ctx context.Context) (int64, error) {
+	deleted, err := us.repo.DeleteStaleSessions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("delete stale sessions error: %w", err)
+	}
+	return deleted, nil
+}
+
+func issueTokens(login, role, sessionID, refresh string) (*models.Tokens, error) {
+	cnfg := config.GetConfig()
+
+	access, err := generateToken(login, role, sessionID, cnfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Tokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    cnfg.AccessTokenTTL,
+	}, nil
+}
+
+// hashRefreshToken keeps only digest in the database. Tokens are random, so plain sha256 is enough.
+func hashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate random error: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
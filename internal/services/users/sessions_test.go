@@ -0,0 +1,138 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/keyring"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/morzisorn/gofermart/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSession struct {
+	login    string
+	refresh  []byte
+	previous []byte
+	revoked  bool
+}
+
+// stubRepository keeps users and sessions in memory, other methods are not used by these tests
+type stubRepository struct {
+	repositories.Repository
+	sessions map[string]*stubSession
+}
+
+func newStubRepository() *stubRepository {
+	return &stubRepository{sessions: map[string]*stubSession{}}
+}
+
+func (r *stubRepository) GetUser(_ context.Context, login string) (*models.User, error) {
+	return &models.User{Login: login, Role: models.RoleUser}, nil
+}
+
+func (r *stubRepository) CreateSession(_ context.Context, id, login string, refreshHash []byte, _ int) error {
+	r.sessions[id] = &stubSession{login: login, refresh: refreshHash}
+	return nil
+}
+
+func (r *stubRepository) GetSessionByRefreshHash(_ context.Context, refreshHash []byte) (*models.Session, error) {
+	for id, s := range r.sessions {
+		if bytes.Equal(s.refresh, refreshHash) || bytes.Equal(s.previous, refreshHash) {
+			return &models.Session{ID: id, UserLogin: s.login, RefreshHash: s.refresh, Revoked: s.revoked}, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *stubRepository) RotateSession(_ context.Context, id string, refreshHash, newRefreshHash []byte, _ int) (bool, error) {
+	s := r.sessions[id]
+	if s == nil || s.revoked || !bytes.Equal(s.refresh, refreshHash) {
+		return false, nil
+	}
+	s.previous, s.refresh = s.refresh, newRefreshHash
+	return true, nil
+}
+
+func (r *stubRepository) RevokeSession(_ context.Context, id string) error {
+	if s := r.sessions[id]; s != nil {
+		s.revoked = true
+	}
+	return nil
+}
+
+func (r *stubRepository) IsSessionActive(_ context.Context, id string) (bool, error) {
+	s := r.sessions[id]
+	return s != nil && !s.revoked, nil
+}
+
+func TestRefreshSession(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, keyring.Init(&config.Config{JWTAlgorithm: "HS256", SecretKey: "test"}))
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, us *UserService, repo *stubRepository, first *models.Tokens)
+	}{
+		{
+			name: "rotation rejects old token",
+			run: func(t *testing.T, us *UserService, repo *stubRepository, first *models.Tokens) {
+				second, err := us.RefreshSession(ctx, first.RefreshToken)
+				require.NoError(t, err)
+				assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+				_, err = us.RefreshSession(ctx, first.RefreshToken)
+				assert.True(t, errors.Is(err, errs.ErrInvalidRefreshToken), err)
+			},
+		},
+		{
+			name: "reuse of rotated token revokes session",
+			run: func(t *testing.T, us *UserService, repo *stubRepository, first *models.Tokens) {
+				second, err := us.RefreshSession(ctx, first.RefreshToken)
+				require.NoError(t, err)
+
+				_, err = us.RefreshSession(ctx, first.RefreshToken)
+				assert.True(t, errors.Is(err, errs.ErrInvalidRefreshToken), err)
+
+				_, err = us.RefreshSession(ctx, second.RefreshToken)
+				assert.True(t, errors.Is(err, errs.ErrInvalidRefreshToken), "current token of revoked session: %v", err)
+			},
+		},
+		{
+			name: "logout deactivates session",
+			run: func(t *testing.T, us *UserService, repo *stubRepository, first *models.Tokens) {
+				var id string
+				for sid := range repo.sessions {
+					id = sid
+				}
+				require.NoError(t, us.Logout(ctx, id))
+
+				active, err := us.IsSessionActive(ctx, id)
+				require.NoError(t, err)
+				assert.False(t, active)
+
+				_, err = us.RefreshSession(ctx, first.RefreshToken)
+				assert.True(t, errors.Is(err, errs.ErrInvalidRefreshToken), err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newStubRepository()
+			us := NewUserService(repo, nil, nil, nil)
+
+			first, err := us.createSession(ctx, "user", models.RoleUser)
+			require.NoError(t, err)
+			require.Len(t, repo.sessions, 1)
+
+			tt.run(t, us, repo, first)
+		})
+	}
+}
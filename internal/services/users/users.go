@@ -8,16 +8,26 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/morzisorn/gofermart/internal/errs"
 	"github.com/morzisorn/gofermart/internal/hash"
+	"github.com/morzisorn/gofermart/internal/logger"
 	"github.com/morzisorn/gofermart/internal/models"
 	"github.com/morzisorn/gofermart/internal/repositories"
+	"go.uber.org/zap"
 )
 
 type UserService struct {
-	repo repositories.Repository
+	repo      repositories.Repository
+	ledger    LedgerBalanceGetter
+	validator Validator
+	guard     LoginGuard
 }
 
-func NewUserService(repo repositories.Repository) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(repo repositories.Repository, ledger LedgerBalanceGetter, validator Validator, guard LoginGuard) *UserService {
+	return &UserService{
+		repo:      repo,
+		ledger:    ledger,
+		validator: validator,
+		guard:     guard,
+	}
 }
 
 func (us *UserService) GetUser(ctx context.Context, user *models.User) (*models.User, error) {
@@ -32,48 +42,173 @@ func (us *UserService) GetUser(ctx context.Context, user *models.User) (*models.
 	return nil, fmt.Errorf("get user error: %w", err)
 }
 
-func (us *UserService) RegisterUser(ctx context.Context, user *models.ParseUserRegister) (string, error) {
+func (us *UserService) RegisterUser(ctx context.Context, user *models.ParseUserRegister) (*models.Tokens, error) {
+	if err := us.validator.ValidateRegistration(user); err != nil {
+		return nil, fmt.Errorf("register user error: %w", err)
+	}
+
 	_, err := us.GetUser(ctx, &models.User{Login: user.Login})
 	switch {
 	case err == nil:
-		return "", fmt.Errorf("register user error: %w", errs.ErrUserAlreadyRegistered)
+		return nil, fmt.Errorf("register user error: %w", errs.ErrUserAlreadyRegistered)
 	case !errors.Is(err, errs.ErrUserNotFound):
-		return "", fmt.Errorf("register user error: %w", err)
+		return nil, fmt.Errorf("register user error: %w", err)
+	}
+
+	hash, err := hash.HashPassword(user.Password)
+	if err != nil {
+		return nil, fmt.Errorf("register user error: %w", err)
 	}
 
-	hash := hash.GetHash([]byte(user.Password))
 	err = us.repo.RegisterUser(ctx, &models.User{
 		Login:    user.Login,
 		Password: hash,
 	})
 	if err != nil {
-		return "", fmt.Errorf("register user error: %w", err)
+		return nil, fmt.Errorf("register user error: %w", err)
 	}
 
-	token, err := generateToken(user.Login)
+	tokens, err := us.createSession(ctx, user.Login, models.RoleUser)
 	if err != nil {
-		return "", fmt.Errorf("register user error: %w", err)
+		return nil, fmt.Errorf("register user error: %w", err)
 	}
 
-	return token, nil
+	return tokens, nil
 }
 
-func (us *UserService) LoginUser(ctx context.Context, user *models.ParseUserRegister) (string, error) {
+// LoginUser checks credentials of client ip. Attempts are counted before the check
+// and lock login or ip out after configured limits.
+func (us *UserService) LoginUser(ctx context.Context, user *models.ParseUserRegister, ip string) (*models.Tokens, error) {
+	if err := us.guard.Attempt(ctx, user.Login, ip); err != nil {
+		return nil, fmt.Errorf("login error: %w", err)
+	}
+
 	dbUser, err := us.GetUser(ctx, &models.User{
 		Login: user.Login,
 	})
 	switch {
 	case errors.Is(err, errs.ErrUserNotFound):
-		return "", fmt.Errorf("login error: %w", errs.ErrIncorrectCredentials)
+		hash.VerifyDummy(user.Password)
+		return nil, fmt.Errorf("login error: %w", errs.ErrIncorrectCredentials)
 	case err != nil:
-		return "", fmt.Errorf("login error: %w", errs.ErrInternalServerError)
+		us.releaseAttempt(ctx, user.Login, ip)
+		return nil, fmt.Errorf("login error: %w", errs.ErrInternalServerError)
+	}
+
+	ok, needsRehash, err := hash.VerifyPassword(user.Password, dbUser.Password)
+	switch {
+	case err != nil:
+		us.releaseAttempt(ctx, user.Login, ip)
+		return nil, fmt.Errorf("login error: %w", err)
+	case !ok:
+		return nil, fmt.Errorf("login error: %w", errs.ErrIncorrectCredentials)
+	}
+
+	if err := us.guard.Success(ctx, user.Login, ip); err != nil {
+		logger.Log.Error("Failed to reset login attempts", zap.String("login", user.Login), zap.Error(err))
+	}
+
+	if needsRehash {
+		us.rehashPassword(ctx, user)
 	}
 
-	if hash := hash.GetHash([]byte(user.Password)); hash != dbUser.Password {
-		return "", fmt.Errorf("login error: %w", errs.ErrIncorrectCredentials)
+	tokens, err := us.createSession(ctx, dbUser.Login, dbUser.Role)
+	if err != nil {
+		return nil, fmt.Errorf("login error: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// releaseAttempt uncounts attempt which failed before credentials were checked.
+// Failure of the counter store does not change the response.
+func (us *UserService) releaseAttempt(ctx context.Context, login, ip string) {
+	if err := us.guard.Release(ctx, login, ip); err != nil {
+		logger.Log.Error("Failed to release login attempt", zap.String("login", login), zap.String("ip", ip), zap.Error(err))
 	}
+}
 
-	return generateToken(user.Login)
+// rehashPassword upgrades stored hash to current algorithm. Failure does not block login.
+func (us *UserService) rehashPassword(ctx context.Context, user *models.ParseUserRegister) {
+	newHash, err := hash.HashPassword(user.Password)
+	if err == nil {
+		err = us.repo.UpdateUserPassword(ctx, user.Login, newHash)
+	}
+	if err != nil {
+		logger.Log.Error("Failed to rehash password", zap.String("login", user.Login), zap.Error(err))
+	}
+}
+
+// SetUserRole changes role and revokes sessions of the user,
+// so tokens with the old role stop working right away
+func (us *UserService) SetUserRole(ctx context.Context, login, role string) error {
+	switch role {
+	case models.RoleUser, models.RoleSupport, models.RoleAdmin:
+	default:
+		return fmt.Errorf("set user role error: %w", errs.ErrUnknownRole)
+	}
+
+	if err := us.repo.SetUserRole(ctx, login, role); err != nil {
+		return fmt.Errorf("set user role error: %w", err)
+	}
+
+	if err := us.repo.RevokeUserSessions(ctx, login, ""); err != nil {
+		return fmt.Errorf("set user role error: %w", err)
+	}
+
+	logger.Log.Info("User role changed", zap.String("login", login), zap.String("role", role))
+	return nil
+}
+
+// ChangePassword requires current password and logs user out of all other sessions
+func (us *UserService) ChangePassword(ctx context.Context, login, sessionID string, req *models.ChangePassword) error {
+	dbUser, err := us.GetUser(ctx, &models.User{Login: login})
+	if err != nil {
+		return fmt.Errorf("change password error: %w", err)
+	}
+
+	ok, _, err := hash.VerifyPassword(req.CurrentPassword, dbUser.Password)
+	switch {
+	case err != nil:
+		return fmt.Errorf("change password error: %w", err)
+	case !ok:
+		return fmt.Errorf("change password error: %w", errs.ErrIncorrectPassword)
+	}
+
+	if err := us.validator.ValidatePasswordChange(login, req); err != nil {
+		return fmt.Errorf("change password error: %w", err)
+	}
+
+	newHash, err := hash.HashPassword(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("change password error: %w", err)
+	}
+
+	if err := us.repo.UpdateUserPassword(ctx, login, newHash); err != nil {
+		return fmt.Errorf("change password error: %w", err)
+	}
+
+	if err := us.RevokeOtherSessions(ctx, login, sessionID); err != nil {
+		return fmt.Errorf("change password error: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUser closes account. History stays for accounting under anonymous login,
+// the original login becomes free. Positive balance blocks deletion unless forfeited.
+func (us *UserService) DeleteUser(ctx context.Context, login string, forfeit bool) error {
+	suffix, err := randomHex(16)
+	if err != nil {
+		return fmt.Errorf("delete user error: %w", err)
+	}
+
+	if err := us.repo.DeleteUser(ctx, login, "deleted_"+suffix, forfeit); err != nil {
+		return fmt.Errorf("delete user error: %w", err)
+	}
+
+	logger.Log.Info("User account deleted", zap.String("login", login), zap.Bool("forfeit", forfeit))
+	return nil
 }
 
 func (us *UserService) GetBalance(ctx context.Context, user *models.User) (*models.UserBalance, error) {
@@ -82,8 +217,21 @@ func (us *UserService) GetBalance(ctx context.Context, user *models.User) (*mode
 		return nil, err
 	}
 
-	return &models.UserBalance{
-		Current:   user.Current,
-		Withdrawn: user.Withdrawn,
-	}, nil
+	// Ledger is the source of truth, counters on users are kept for fast checks
+	balance, err := us.ledger.GetBalance(ctx, user.Login)
+	if err != nil {
+		return nil, fmt.Errorf("get balance error: %w", err)
+	}
+
+	if balance.Current != user.Current || balance.Withdrawn != user.Withdrawn {
+		logger.Log.Warn("User balance counters differ from ledger",
+			zap.String("login", user.Login),
+			zap.String("current", user.Current.String()),
+			zap.String("ledger_current", balance.Current.String()),
+			zap.String("withdrawn", user.Withdrawn.String()),
+			zap.String("ledger_withdrawn", balance.Withdrawn.String()),
+		)
+	}
+
+	return balance, nil
 }
@@ -0,0 +1,172 @@
+package validation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/models"
+)
+
+// users.login column size, longer logins can not be stored whatever the config says
+const maxLoginColumnLength = 50
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error lists every violated rule so client can show all problems at once
+type Error struct {
+	Fields []FieldError `json:"errors"`
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "validation error: " + strings.Join(msgs, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	return errs.ErrValidation
+}
+
+func (e *Error) add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e *Error) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+type Validator struct {
+	loginPattern      *regexp.Regexp
+	loginMinLength    int
+	loginMaxLength    int
+	passwordMinLength int
+	passwordMinClass  int
+	denylist          map[string]struct{}
+}
+
+func NewValidator(cnfg *config.Config) (*Validator, error) {
+	pattern, err := regexp.Compile(cnfg.LoginPattern)
+	if err != nil {
+		return nil, fmt.Errorf("compile login pattern error: %w", err)
+	}
+
+	v := &Validator{
+		loginPattern:      pattern,
+		loginMinLength:    cnfg.LoginMinLength,
+		loginMaxLength:    min(cnfg.LoginMaxLength, maxLoginColumnLength),
+		passwordMinLength: cnfg.PasswordMinLength,
+		passwordMinClass:  cnfg.PasswordMinClasses,
+		denylist:          map[string]struct{}{},
+	}
+
+	if cnfg.PasswordDenylistPath != "" {
+		v.denylist, err = loadDenylist(cnfg.PasswordDenylistPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func (v *Validator) ValidateRegistration(user *models.ParseUserRegister) error {
+	e := &Error{}
+	v.checkLogin(e, user.Login)
+	v.checkPassword(e, "password", user.Password, user.Login)
+	return e.orNil()
+}
+
+func (v *Validator) ValidatePasswordChange(login string, req *models.ChangePassword) error {
+	e := &Error{}
+	v.checkPassword(e, "new_password", req.NewPassword, login)
+	return e.orNil()
+}
+
+func (v *Validator) checkLogin(e *Error, login string) {
+	length := utf8.RuneCountInString(login)
+	switch {
+	case length < v.loginMinLength:
+		e.add("login", "must be at least %d characters", v.loginMinLength)
+	case length > v.loginMaxLength:
+		e.add("login", "must be at most %d characters", v.loginMaxLength)
+	}
+
+	if login != "" && !v.loginPattern.MatchString(login) {
+		e.add("login", "contains not allowed characters")
+	}
+}
+
+func (v *Validator) checkPassword(e *Error, field, password, login string) {
+	if utf8.RuneCountInString(password) < v.passwordMinLength {
+		e.add(field, "must be at least %d characters", v.passwordMinLength)
+	}
+
+	if classes := characterClasses(password); classes < v.passwordMinClass {
+		e.add(field, "must contain at least %d of: lowercase letters, uppercase letters, digits, other characters", v.passwordMinClass)
+	}
+
+	if login != "" && strings.EqualFold(password, login) {
+		e.add(field, "must differ from login")
+	}
+
+	if _, ok := v.denylist[strings.ToLower(password)]; ok {
+		e.add(field, "is found in breached passwords list")
+	}
+}
+
+func characterClasses(s string) int {
+	var lower, upper, digit, other int
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
+
+// loadDenylist reads breached passwords, comparison is case-insensitive
+func loadDenylist(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open password denylist error: %w", err)
+	}
+	defer f.Close()
+
+	denylist := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denylist[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read password denylist error: %w", err)
+	}
+
+	return denylist, nil
+}
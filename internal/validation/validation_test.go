@@ -0,0 +1,80 @@
+package validation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/morzisorn/gofermart/config"
+	"github.com/morzisorn/gofermart/internal/errs"
+	"github.com/morzisorn/gofermart/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestValidator(t *testing.T) *Validator {
+	t.Helper()
+
+	denylist := filepath.Join(t.TempDir(), "denylist.txt")
+	require.NoError(t, os.WriteFile(denylist, []byte("# breached\nPassword123\n"), 0o600))
+
+	v, err := NewValidator(&config.Config{
+		LoginPattern:         `^[a-zA-Z0-9_.-]+$`,
+		LoginMinLength:       3,
+		LoginMaxLength:       100,
+		PasswordMinLength:    8,
+		PasswordMinClasses:   2,
+		PasswordDenylistPath: denylist,
+	})
+	require.NoError(t, err)
+	return v
+}
+
+func TestValidateRegistration(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		name   string
+		user   models.ParseUserRegister
+		fields []string
+	}{
+		{name: "valid", user: models.ParseUserRegister{Login: "user_1", Password: "secret42"}},
+		{name: "empty", user: models.ParseUserRegister{}, fields: []string{"login", "password", "password"}},
+		{name: "login too long", user: models.ParseUserRegister{Login: strings.Repeat("a", 51), Password: "secret42"}, fields: []string{"login"}},
+		{name: "login characters", user: models.ParseUserRegister{Login: "user name", Password: "secret42"}, fields: []string{"login"}},
+		{name: "weak password", user: models.ParseUserRegister{Login: "user", Password: "password"}, fields: []string{"password"}},
+		{name: "password equals login", user: models.ParseUserRegister{Login: "User1234", Password: "user1234"}, fields: []string{"password"}},
+		{name: "breached password", user: models.ParseUserRegister{Login: "user", Password: "PASSWORD123"}, fields: []string{"password"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateRegistration(&tt.user)
+			if len(tt.fields) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, errs.ErrValidation))
+
+			var verr *Error
+			require.True(t, errors.As(err, &verr))
+
+			fields := make([]string, 0, len(verr.Fields))
+			for _, f := range verr.Fields {
+				fields = append(fields, f.Field)
+			}
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
+
+func TestMissingDenylist(t *testing.T) {
+	_, err := NewValidator(&config.Config{
+		LoginPattern:         ".*",
+		PasswordDenylistPath: filepath.Join(t.TempDir(), "missing.txt"),
+	})
+	assert.Error(t, err)
+}